@@ -0,0 +1,164 @@
+package auction_house
+
+import (
+	"encoding/binary"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+	"sort"
+)
+
+const (
+	bidReceiptAuctionHouseOffset = 8 + 32*2
+	bidReceiptBuyerOffset        = 8 + 32*3
+	bidReceiptMetadataOffset     = 8 + 32*4
+)
+
+// GetBids returns bids on mint which have a receipt and are neither cancelled nor
+// filled, sorted from the highest price.
+func (aucHouse *AuctionHouseActor) GetBids(mint solana.PublicKey) ([]AuctionHouseBid, error) {
+	metadata, err := getMetadata(mint)
+	if err != nil {
+		return nil, err
+	}
+	bids, err := aucHouse.getBids(rpc.RPCFilterMemcmp{Offset: bidReceiptMetadataOffset, Bytes: metadata.Bytes()})
+	if err != nil {
+		return nil, errors.Errorf("failed to get bid receipts of %s. err: %s", mint.String(), err.Error())
+	}
+	return bids, nil
+}
+
+// GetBuyerBids returns bids of buyer on any mint which have a receipt and are
+// neither cancelled nor filled, sorted from the highest price.
+func (aucHouse *AuctionHouseActor) GetBuyerBids(buyer solana.PublicKey) ([]AuctionHouseBid, error) {
+	bids, err := aucHouse.getBids(rpc.RPCFilterMemcmp{Offset: bidReceiptBuyerOffset, Bytes: buyer.Bytes()})
+	if err != nil {
+		return nil, errors.Errorf("failed to get bid receipts of %s. err: %s", buyer.String(), err.Error())
+	}
+	return bids, nil
+}
+
+func (aucHouse *AuctionHouseActor) getBids(filter rpc.RPCFilterMemcmp) ([]AuctionHouseBid, error) {
+	accounts, err := aucHouse.Wm.Client.GetProgramAccountsWithOpts(aucHouse.Wm.Context, auction_house_types.ProgramID, &rpc.GetProgramAccountsOpts{
+		Commitment: aucHouse.Wm.Commitment,
+		Encoding:   solana.EncodingBase64,
+		Filters: []rpc.RPCFilter{
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: auction_house_types.BidReceiptDiscriminator[:]}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: bidReceiptAuctionHouseOffset, Bytes: aucHouse.AuctionHouseAccount.Bytes()}},
+			{Memcmp: &filter},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var bids []AuctionHouseBid
+	for _, account := range accounts {
+		var receipt auction_house_types.BidReceipt
+		if err = bin.NewBorshDecoder(account.Account.Data.GetBinary()).Decode(&receipt); err != nil {
+			return nil, errors.Errorf("failed to decode bid receipt %s. err: %s", account.Pubkey.String(), err.Error())
+		}
+		if receipt.CanceledAt != nil || receipt.PurchaseReceipt != nil {
+			continue
+		}
+		bids = append(bids, AuctionHouseBid{
+			Receipt:      account.Pubkey,
+			TradeState:   receipt.TradeState,
+			Buyer:        receipt.Buyer,
+			TokenAccount: receipt.TokenAccount,
+			Price:        receipt.Price,
+			TokenSize:    receipt.TokenSize,
+			CreatedAt:    receipt.CreatedAt,
+		})
+	}
+	sort.SliceStable(bids, func(i, j int) bool {
+		if bids[i].Price != bids[j].Price {
+			return bids[i].Price > bids[j].Price
+		}
+		return bids[i].CreatedAt < bids[j].CreatedAt
+	})
+	return bids, nil
+}
+
+// AcceptBestBid sells the seller's tokens of mint to the highest bid whose trade
+// state is still open and whose buyer escrow covers the price together with the
+// other open bids of the buyer. The listing and the sale happen in one transaction.
+func (aucHouse *AuctionHouseActor) AcceptBestBid(
+	seller solana.PrivateKey,
+	mint solana.PublicKey,
+) (AuctionHouseBid, *wallet_manager.OperationResult, error) {
+	mintAta, _, err := solana.FindAssociatedTokenAddress(seller.PublicKey(), mint)
+	if err != nil {
+		return AuctionHouseBid{}, nil, err
+	}
+	holding, err := aucHouse.tokenBalance(mintAta)
+	if err != nil {
+		return AuctionHouseBid{}, nil, err
+	}
+	bids, err := aucHouse.GetBids(mint)
+	if err != nil {
+		return AuctionHouseBid{}, nil, err
+	}
+	var candidates []AuctionHouseBid
+	var tradeStates []solana.PublicKey
+	for _, bid := range bids {
+		if bid.TokenSize > holding || (!bid.IsPublic() && !bid.TokenAccount.Equals(mintAta)) {
+			continue
+		}
+		candidates = append(candidates, bid)
+		tradeStates = append(tradeStates, bid.TradeState)
+	}
+	if len(candidates) == 0 {
+		return AuctionHouseBid{}, nil, errors.Errorf("no active bids for %s", mint.String())
+	}
+	openTradeStates, err := aucHouse.Wm.Client.GetMultipleAccounts(aucHouse.Wm.Context, tradeStates...)
+	if err != nil {
+		return AuctionHouseBid{}, nil, errors.Errorf("failed to get bid trade states. err: %s", err.Error())
+	}
+	for idx, bid := range candidates {
+		if idx >= len(openTradeStates.Value) || openTradeStates.Value[idx] == nil {
+			continue
+		}
+		escrow, err := aucHouse.GetEscrowBalance(bid.Buyer)
+		if err != nil {
+			return AuctionHouseBid{}, nil, err
+		}
+		// the escrow backs every open bid of the buyer, this one included
+		committed, err := aucHouse.openBidsTotal(bid.Buyer)
+		if err != nil {
+			return AuctionHouseBid{}, nil, err
+		}
+		if committed < bid.Price {
+			committed = bid.Price
+		}
+		if escrow < committed {
+			continue
+		}
+		result, err := aucHouse.AcceptBid(seller, AuctionHouseAcceptBidData{
+			Buyer:       bid.Buyer,
+			MintAddress: mint,
+			Price:       bid.Price,
+			TokenSize:   bid.TokenSize,
+			Public:      bid.IsPublic(),
+		})
+		return bid, result, err
+	}
+	return AuctionHouseBid{}, nil, errors.Errorf("no bid for %s is backed by its buyer escrow", mint.String())
+}
+
+func (aucHouse *AuctionHouseActor) tokenBalance(tokenAccount solana.PublicKey) (uint64, error) {
+	account, err := aucHouse.Wm.Client.GetAccountInfo(aucHouse.Wm.Context, tokenAccount)
+	if err == rpc.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Errorf("failed to get token account %s. err: %s", tokenAccount.String(), err.Error())
+	}
+	data := account.Value.Data.GetBinary()
+	if len(data) < 72 {
+		return 0, errors.Errorf("%s is not a token account", tokenAccount.String())
+	}
+	return binary.LittleEndian.Uint64(data[64:72]), nil
+}
@@ -0,0 +1,98 @@
+package auction_house
+
+import (
+	token_metadata "github.com/gagliardetto/metaplex-go/clients/token-metadata"
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/auction_house/auction_house_types"
+	"testing"
+)
+
+func TestAuctionHouseActor_AcceptBestBid(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:    solana.NewWallet().PublicKey(),
+		TreasuryMint: solana.SolMint,
+	})
+	seller := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	creator := solana.NewWallet().PublicKey()
+	sellerAta, _, _ := solana.FindAssociatedTokenAddress(seller.PublicKey(), mint)
+	fake.SetTokenAccount(sellerAta, mint, seller.PublicKey(), 1)
+	metadata, _ := getMetadata(mint)
+	creators := []token_metadata.Creator{{Address: creator, Verified: true, Share: 100}}
+	fake.setMetadata(metadata, token_metadata.Metadata{
+		Key:  token_metadata.KeyMetadataV1,
+		Mint: mint,
+		Data: token_metadata.Data{SellerFeeBasisPoints: 500, Creators: &creators},
+	})
+
+	addBid := func(price uint64, escrowLamports uint64, open bool, canceled bool) solana.PublicKey {
+		buyer := solana.NewWallet().PublicKey()
+		tradeState, _, _ := actor.getPublicTradeState(buyer, mint, price, 1)
+		receipt, _, _ := getBidReceipt(tradeState)
+		data := auction_house_types.BidReceipt{
+			TradeState:   tradeState,
+			AuctionHouse: actor.AuctionHouseAccount,
+			Buyer:        buyer,
+			Metadata:     metadata,
+			Price:        price,
+			TokenSize:    1,
+		}
+		if canceled {
+			canceledAt := int64(1)
+			data.CanceledAt = &canceledAt
+		}
+		fake.setProgramAccount(receipt, &data)
+		if open {
+			fake.SetAccount(tradeState, auction_house_types.ProgramID, 1, []byte{255})
+		}
+		escrow, _, _ := actor.GetBuyerEscrow(buyer)
+		fake.SetAccount(escrow, solana.SystemProgramID, escrowLamports, nil)
+		return buyer
+	}
+	addBid(5000, 5000, true, true)
+	addBid(4000, 4000, false, false)
+	addBid(3000, 100, true, false)
+	// the escrow covers this bid but not together with a bid on another mint
+	overcommitted := addBid(2500, 2500, true, false)
+	otherMint := solana.NewWallet().PublicKey()
+	otherMetadata, _ := getMetadata(otherMint)
+	otherTradeState, _, _ := actor.getPublicTradeState(overcommitted, otherMint, 1000, 1)
+	otherReceipt, _, _ := getBidReceipt(otherTradeState)
+	fake.setProgramAccount(otherReceipt, &auction_house_types.BidReceipt{
+		TradeState:   otherTradeState,
+		AuctionHouse: actor.AuctionHouseAccount,
+		Buyer:        overcommitted,
+		Metadata:     otherMetadata,
+		Price:        1000,
+		TokenSize:    1,
+	})
+	fake.SetAccount(otherTradeState, auction_house_types.ProgramID, 1, []byte{255})
+	best := addBid(2000, 2000, true, false)
+	addBid(1000, 1000, true, false)
+
+	bids, err := actor.GetBids(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bids) != 5 || bids[0].Price != 4000 {
+		t.Fatalf("unexpected bids %+v", bids)
+	}
+	bid, _, err := actor.AcceptBestBid(seller, mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bid.Buyer.Equals(best) || bid.Price != 2000 {
+		t.Fatalf("expected bid of %s at 2000, got %+v", best, bid)
+	}
+	tx := fake.LastSent()
+	sale := auction_house_types.NewExecuteSaleInstructionBuilder()
+	sale.AccountMetaSlice = tx.Message.Instructions[1].ResolveInstructionAccounts(&tx.Message)
+	buyerAta, _, _ := solana.FindAssociatedTokenAddress(best, mint)
+	if !sale.GetBuyerReceiptTokenAccountAccount().PublicKey.Equals(buyerAta) {
+		t.Fatalf("unexpected buyer receipt account %s", sale.GetBuyerReceiptTokenAccountAccount().PublicKey)
+	}
+	if len(sale.AccountMetaSlice) != 22 || !sale.AccountMetaSlice[21].PublicKey.Equals(creator) {
+		t.Fatalf("expected creator %s to be appended, got %v", creator, sale.AccountMetaSlice)
+	}
+}
@@ -0,0 +1,282 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+)
+
+func FindAuctionHouse(authority, treasuryMint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress(
+		[][]byte{[]byte(auctionHouse), authority.Bytes(), treasuryMint.Bytes()},
+		auction_house_types.ProgramID,
+	)
+}
+
+func FindAuctionHouseFeeAccount(auctionHouseAccount solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress(
+		[][]byte{[]byte(auctionHouse), auctionHouseAccount.Bytes(), []byte(feePayer)},
+		auction_house_types.ProgramID,
+	)
+}
+
+func FindAuctionHouseTreasury(auctionHouseAccount solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress(
+		[][]byte{[]byte(auctionHouse), auctionHouseAccount.Bytes(), []byte(treasury)},
+		auction_house_types.ProgramID,
+	)
+}
+
+// CreateAuctionHouse creates the auction house of authority for data.TreasuryMint
+// and returns an actor for it. The authority pays for the new accounts.
+func CreateAuctionHouse(
+	wm *wallet_manager.WalletManager,
+	authority solana.PrivateKey,
+	data AuctionHouseCreateData,
+) (*AuctionHouseActor, *wallet_manager.OperationResult, error) {
+	if data.SellerFeeBasisPoints > 10000 {
+		return nil, nil, errors.Errorf("seller fee basis points %d exceed 10000", data.SellerFeeBasisPoints)
+	}
+	if data.TreasuryMint.IsZero() {
+		data.TreasuryMint = solana.SolMint
+	}
+	if data.FeeWithdrawalDestination.IsZero() {
+		data.FeeWithdrawalDestination = authority.PublicKey()
+	}
+	if data.TreasuryWithdrawalDestinationOwner.IsZero() {
+		data.TreasuryWithdrawalDestinationOwner = authority.PublicKey()
+	}
+	auctionHouseAccount, bump, err := FindAuctionHouse(authority.PublicKey(), data.TreasuryMint)
+	if err != nil {
+		return nil, nil, err
+	}
+	feeAccount, feePayerBump, err := FindAuctionHouseFeeAccount(auctionHouseAccount)
+	if err != nil {
+		return nil, nil, err
+	}
+	treasuryAccount, treasuryBump, err := FindAuctionHouseTreasury(auctionHouseAccount)
+	if err != nil {
+		return nil, nil, err
+	}
+	exists, err := accountExists(wm, auctionHouseAccount)
+	if err != nil {
+		return nil, nil, errors.Errorf("failed to get auction house %s. err: %s", auctionHouseAccount.String(), err.Error())
+	}
+	if exists {
+		return nil, nil, errors.Errorf("auction house %s already exists", auctionHouseAccount.String())
+	}
+	aucHouse := &AuctionHouseActor{
+		Wm:                  wm,
+		AuctionHouseAccount: auctionHouseAccount,
+		AuctionHouseData: auction_house_types.AuctionHouse{
+			AuctionHouseFeeAccount:   feeAccount,
+			AuctionHouseTreasury:     treasuryAccount,
+			FeeWithdrawalDestination: data.FeeWithdrawalDestination,
+			TreasuryMint:             data.TreasuryMint,
+			Authority:                authority.PublicKey(),
+			Creator:                  authority.PublicKey(),
+			Bump:                     bump,
+			TreasuryBump:             treasuryBump,
+			FeePayerBump:             feePayerBump,
+			SellerFeeBasisPoints:     data.SellerFeeBasisPoints,
+			RequiresSignOff:          data.RequiresSignOff,
+			CanChangeSalePrice:       data.CanChangeSalePrice,
+		},
+	}
+	aucHouse.AuctionHouseData.TreasuryWithdrawalDestination, err = aucHouse.paymentAccount(data.TreasuryWithdrawalDestinationOwner)
+	if err != nil {
+		return nil, nil, err
+	}
+	instruction := auction_house_types.NewCreateAuctionHouseInstructionBuilder().
+		SetBump(bump).
+		SetFeePayerBump(feePayerBump).
+		SetTreasuryBump(treasuryBump).
+		SetSellerFeeBasisPoints(data.SellerFeeBasisPoints).
+		SetRequiresSignOff(data.RequiresSignOff).
+		SetCanChangeSalePrice(data.CanChangeSalePrice).
+		SetTreasuryMintAccount(data.TreasuryMint).
+		SetPayerAccount(authority.PublicKey()).
+		SetAuthorityAccount(authority.PublicKey()).
+		SetFeeWithdrawalDestinationAccount(data.FeeWithdrawalDestination).
+		SetTreasuryWithdrawalDestinationAccount(aucHouse.AuctionHouseData.TreasuryWithdrawalDestination).
+		SetTreasuryWithdrawalDestinationOwnerAccount(data.TreasuryWithdrawalDestinationOwner).
+		SetAuctionHouseAccount(auctionHouseAccount).
+		SetAuctionHouseFeeAccountAccount(feeAccount).
+		SetAuctionHouseTreasuryAccount(treasuryAccount).
+		SetTokenProgramAccount(solana.TokenProgramID).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		SetAtaProgramAccount(solana.SPLAssociatedTokenAccountProgramID).
+		SetRentAccount(solana.SysVarRentPubkey).
+		Build()
+	result, err := wm.SendAndConfirmInstructions(
+		authority.PublicKey(),
+		[]solana.Instruction{instruction},
+		[]solana.PrivateKey{authority},
+	)
+	if err != nil {
+		return nil, result, err
+	}
+	return aucHouse, result, nil
+}
+
+// UpdateAuctionHouse changes the settings of the auction house. AuctionHouseData
+// is updated once the transaction is confirmed.
+func (aucHouse *AuctionHouseActor) UpdateAuctionHouse(
+	authority solana.PrivateKey,
+	data AuctionHouseUpdateData,
+) (*wallet_manager.OperationResult, error) {
+	if data.SellerFeeBasisPoints != nil && *data.SellerFeeBasisPoints > 10000 {
+		return nil, errors.Errorf("seller fee basis points %d exceed 10000", *data.SellerFeeBasisPoints)
+	}
+	if err := aucHouse.checkAuthority(authority); err != nil {
+		return nil, err
+	}
+	updated := aucHouse.AuctionHouseData
+	if !data.NewAuthority.IsZero() {
+		updated.Authority = data.NewAuthority
+	}
+	if !data.FeeWithdrawalDestination.IsZero() {
+		updated.FeeWithdrawalDestination = data.FeeWithdrawalDestination
+	}
+	treasuryOwner := data.TreasuryWithdrawalDestinationOwner
+	if treasuryOwner.IsZero() {
+		var err error
+		treasuryOwner, err = aucHouse.treasuryWithdrawalDestinationOwner()
+		if err != nil {
+			return nil, err
+		}
+	}
+	treasuryDestination, err := aucHouse.paymentAccount(treasuryOwner)
+	if err != nil {
+		return nil, err
+	}
+	updated.TreasuryWithdrawalDestination = treasuryDestination
+	builder := auction_house_types.NewUpdateAuctionHouseInstructionBuilder().
+		SetTreasuryMintAccount(aucHouse.AuctionHouseData.TreasuryMint).
+		SetPayerAccount(authority.PublicKey()).
+		SetAuthorityAccount(authority.PublicKey()).
+		SetNewAuthorityAccount(updated.Authority).
+		SetFeeWithdrawalDestinationAccount(updated.FeeWithdrawalDestination).
+		SetTreasuryWithdrawalDestinationAccount(treasuryDestination).
+		SetTreasuryWithdrawalDestinationOwnerAccount(treasuryOwner).
+		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
+		SetTokenProgramAccount(solana.TokenProgramID).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		SetAtaProgramAccount(solana.SPLAssociatedTokenAccountProgramID).
+		SetRentAccount(solana.SysVarRentPubkey)
+	if data.SellerFeeBasisPoints != nil {
+		builder.SetSellerFeeBasisPoints(*data.SellerFeeBasisPoints)
+		updated.SellerFeeBasisPoints = *data.SellerFeeBasisPoints
+	}
+	if data.RequiresSignOff != nil {
+		builder.SetRequiresSignOff(*data.RequiresSignOff)
+		updated.RequiresSignOff = *data.RequiresSignOff
+	}
+	if data.CanChangeSalePrice != nil {
+		builder.SetCanChangeSalePrice(*data.CanChangeSalePrice)
+		updated.CanChangeSalePrice = *data.CanChangeSalePrice
+	}
+	result, err := aucHouse.Wm.SendAndConfirmInstructions(
+		authority.PublicKey(),
+		[]solana.Instruction{builder.Build()},
+		[]solana.PrivateKey{authority},
+	)
+	if err == nil && !result.Simulated {
+		aucHouse.AuctionHouseData = updated
+	}
+	return result, err
+}
+
+// WithdrawFromFee moves lamports from the fee account to the fee withdrawal
+// destination.
+func (aucHouse *AuctionHouseActor) WithdrawFromFee(authority solana.PrivateKey, amount uint64) (*wallet_manager.OperationResult, error) {
+	if err := aucHouse.checkAuthority(authority); err != nil {
+		return nil, err
+	}
+	instruction := auction_house_types.NewWithdrawFromFeeInstructionBuilder().
+		SetAmount(amount).
+		SetAuthorityAccount(authority.PublicKey()).
+		SetFeeWithdrawalDestinationAccount(aucHouse.AuctionHouseData.FeeWithdrawalDestination).
+		SetAuctionHouseFeeAccountAccount(aucHouse.AuctionHouseData.AuctionHouseFeeAccount).
+		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		Build()
+	return aucHouse.Wm.SendAndConfirmInstructions(
+		authority.PublicKey(),
+		[]solana.Instruction{instruction},
+		[]solana.PrivateKey{authority},
+	)
+}
+
+// WithdrawFromTreasury moves collected seller fees to the treasury withdrawal
+// destination.
+func (aucHouse *AuctionHouseActor) WithdrawFromTreasury(authority solana.PrivateKey, amount uint64) (*wallet_manager.OperationResult, error) {
+	if err := aucHouse.checkAuthority(authority); err != nil {
+		return nil, err
+	}
+	instruction := auction_house_types.NewWithdrawFromTreasuryInstructionBuilder().
+		SetAmount(amount).
+		SetTreasuryMintAccount(aucHouse.AuctionHouseData.TreasuryMint).
+		SetAuthorityAccount(authority.PublicKey()).
+		SetTreasuryWithdrawalDestinationAccount(aucHouse.AuctionHouseData.TreasuryWithdrawalDestination).
+		SetAuctionHouseTreasuryAccount(aucHouse.AuctionHouseData.AuctionHouseTreasury).
+		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
+		SetTokenProgramAccount(solana.TokenProgramID).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		Build()
+	return aucHouse.Wm.SendAndConfirmInstructions(
+		authority.PublicKey(),
+		[]solana.Instruction{instruction},
+		[]solana.PrivateKey{authority},
+	)
+}
+
+// GetBalances returns the lamports of the fee account and the treasury balance,
+// which is in base units of the treasury mint for SPL-token auction houses.
+func (aucHouse *AuctionHouseActor) GetBalances() (AuctionHouseBalances, error) {
+	feeBalance, err := aucHouse.Wm.Client.GetBalance(aucHouse.Wm.Context, aucHouse.AuctionHouseData.AuctionHouseFeeAccount, aucHouse.Wm.Commitment)
+	if err != nil {
+		return AuctionHouseBalances{}, errors.Errorf("failed to get fee account balance. err: %s", err.Error())
+	}
+	balances := AuctionHouseBalances{FeeAccount: feeBalance.Value}
+	if !aucHouse.isNativeTreasury() {
+		balances.Treasury, err = aucHouse.tokenBalance(aucHouse.AuctionHouseData.AuctionHouseTreasury)
+		return balances, err
+	}
+	treasuryBalance, err := aucHouse.Wm.Client.GetBalance(aucHouse.Wm.Context, aucHouse.AuctionHouseData.AuctionHouseTreasury, aucHouse.Wm.Commitment)
+	if err != nil {
+		return AuctionHouseBalances{}, errors.Errorf("failed to get treasury balance. err: %s", err.Error())
+	}
+	balances.Treasury = treasuryBalance.Value
+	return balances, nil
+}
+
+func (aucHouse *AuctionHouseActor) checkAuthority(authority solana.PrivateKey) error {
+	if !authority.PublicKey().Equals(aucHouse.AuctionHouseData.Authority) {
+		return errors.Errorf("%s is not the authority of auction house %s", authority.PublicKey().String(), aucHouse.AuctionHouseAccount.String())
+	}
+	return nil
+}
+
+// treasuryWithdrawalDestinationOwner is the destination itself for native SOL
+// houses and the owner of the destination token account otherwise.
+func (aucHouse *AuctionHouseActor) treasuryWithdrawalDestinationOwner() (solana.PublicKey, error) {
+	destination := aucHouse.AuctionHouseData.TreasuryWithdrawalDestination
+	if aucHouse.isNativeTreasury() {
+		return destination, nil
+	}
+	account, err := aucHouse.Wm.Client.GetAccountInfo(aucHouse.Wm.Context, destination)
+	if err == rpc.ErrNotFound {
+		return solana.PublicKey{}, errors.Errorf("treasury withdrawal destination %s does not exist", destination.String())
+	}
+	if err != nil {
+		return solana.PublicKey{}, errors.Errorf("failed to get treasury withdrawal destination %s. err: %s", destination.String(), err.Error())
+	}
+	data := account.Value.Data.GetBinary()
+	if len(data) < 64 {
+		return solana.PublicKey{}, errors.Errorf("%s is not a token account", destination.String())
+	}
+	return solana.PublicKeyFromBytes(data[32:64]), nil
+}
@@ -0,0 +1,144 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/auction_house/auction_house_types"
+	"testing"
+)
+
+func TestCreateAuctionHouse(t *testing.T) {
+	fake := newFakeCluster()
+	wm := fake.actor(auction_house_types.AuctionHouse{}).Wm
+	authority := solana.NewWallet().PrivateKey
+
+	actor, _, err := CreateAuctionHouse(wm, authority, AuctionHouseCreateData{SellerFeeBasisPoints: 250, CanChangeSalePrice: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, bump, _ := FindAuctionHouse(authority.PublicKey(), solana.SolMint)
+	if !actor.AuctionHouseAccount.Equals(expected) || actor.AuctionHouseData.Bump != bump {
+		t.Fatalf("expected auction house %s, got %s", expected, actor.AuctionHouseAccount)
+	}
+	tx := fake.LastSent()
+	accounts := tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	decoded, err := auction_house_types.DecodeInstruction(accounts, tx.Message.Instructions[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := decoded.Impl.(*auction_house_types.CreateAuctionHouse)
+	feeAccount, feePayerBump, _ := FindAuctionHouseFeeAccount(expected)
+	treasuryAccount, treasuryBump, _ := FindAuctionHouseTreasury(expected)
+	if *create.FeePayerBump != feePayerBump || *create.TreasuryBump != treasuryBump || *create.SellerFeeBasisPoints != 250 {
+		t.Fatalf("unexpected create arguments %+v", create)
+	}
+	if !create.GetAuctionHouseFeeAccountAccount().PublicKey.Equals(feeAccount) ||
+		!create.GetAuctionHouseTreasuryAccount().PublicKey.Equals(treasuryAccount) ||
+		!create.GetTreasuryWithdrawalDestinationAccount().PublicKey.Equals(authority.PublicKey()) {
+		t.Fatalf("unexpected create accounts %v", accounts)
+	}
+
+	fake.SetAccount(expected, auction_house_types.ProgramID, 1, nil)
+	if _, _, err = CreateAuctionHouse(wm, authority, AuctionHouseCreateData{}); err == nil {
+		t.Fatal("expected an existing auction house to be rejected")
+	}
+}
+
+func TestAuctionHouseActor_UpdateAuctionHouse(t *testing.T) {
+	fake := newFakeCluster()
+	authority := solana.NewWallet().PrivateKey
+	usdc := solana.NewWallet().PublicKey()
+	treasuryOwner := solana.NewWallet().PublicKey()
+	treasuryDestination, _, _ := solana.FindAssociatedTokenAddress(treasuryOwner, usdc)
+	fake.SetTokenAccount(treasuryDestination, usdc, treasuryOwner, 0)
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:                     authority.PublicKey(),
+		TreasuryMint:                  usdc,
+		TreasuryWithdrawalDestination: treasuryDestination,
+		AuctionHouseFeeAccount:        solana.NewWallet().PublicKey(),
+		AuctionHouseTreasury:          solana.NewWallet().PublicKey(),
+	})
+	fake.SetAccount(actor.AuctionHouseData.AuctionHouseFeeAccount, solana.SystemProgramID, 7000, nil)
+	fake.SetTokenAccount(actor.AuctionHouseData.AuctionHouseTreasury, usdc, actor.AuctionHouseData.AuctionHouseTreasury, 300)
+
+	requiresSignOff := true
+	if _, err := actor.UpdateAuctionHouse(authority, AuctionHouseUpdateData{RequiresSignOff: &requiresSignOff}); err != nil {
+		t.Fatal(err)
+	}
+	if !actor.AuctionHouseData.RequiresSignOff {
+		t.Fatal("expected the auction house data to be updated")
+	}
+	tx := fake.LastSent()
+	accounts := tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	if !accounts[5].PublicKey.Equals(treasuryDestination) || !accounts[6].PublicKey.Equals(treasuryOwner) {
+		t.Fatalf("expected the treasury withdrawal destination to be kept, got %v", accounts)
+	}
+	if _, err := actor.UpdateAuctionHouse(solana.NewWallet().PrivateKey, AuctionHouseUpdateData{}); err == nil {
+		t.Fatal("expected an update by another key to be rejected")
+	}
+
+	balances, err := actor.GetBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances.FeeAccount != 7000 || balances.Treasury != 300 {
+		t.Fatalf("unexpected balances %+v", balances)
+	}
+}
+
+func TestAuctionHouseActor_WithdrawFromFee(t *testing.T) {
+	fake := newFakeCluster()
+	authority := solana.NewWallet().PrivateKey
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:                authority.PublicKey(),
+		TreasuryMint:             solana.SolMint,
+		FeeWithdrawalDestination: solana.NewWallet().PublicKey(),
+		AuctionHouseFeeAccount:   solana.NewWallet().PublicKey(),
+	})
+
+	if _, err := actor.WithdrawFromFee(solana.NewWallet().PrivateKey, 1000); err == nil {
+		t.Fatal("expected a withdrawal by another key to be rejected")
+	}
+	if fake.Calls("sendTransaction") != 0 {
+		t.Fatal("a rejected withdrawal must not be sent")
+	}
+	if _, err := actor.WithdrawFromFee(authority, 1000); err != nil {
+		t.Fatal(err)
+	}
+	withdraw := auction_house_types.NewWithdrawFromFeeInstructionBuilder()
+	tx := fake.LastSent()
+	withdraw.AccountMetaSlice = tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	if !withdraw.GetAuthorityAccount().PublicKey.Equals(authority.PublicKey()) || !withdraw.GetAuthorityAccount().IsSigner ||
+		!withdraw.GetFeeWithdrawalDestinationAccount().PublicKey.Equals(actor.AuctionHouseData.FeeWithdrawalDestination) ||
+		!withdraw.GetAuctionHouseFeeAccountAccount().PublicKey.Equals(actor.AuctionHouseData.AuctionHouseFeeAccount) {
+		t.Fatalf("unexpected withdraw from fee accounts %v", withdraw.AccountMetaSlice)
+	}
+}
+
+func TestAuctionHouseActor_WithdrawFromTreasury(t *testing.T) {
+	fake := newFakeCluster()
+	authority := solana.NewWallet().PrivateKey
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:                     authority.PublicKey(),
+		TreasuryMint:                  solana.SolMint,
+		TreasuryWithdrawalDestination: solana.NewWallet().PublicKey(),
+		AuctionHouseTreasury:          solana.NewWallet().PublicKey(),
+	})
+
+	if _, err := actor.WithdrawFromTreasury(solana.NewWallet().PrivateKey, 1000); err == nil {
+		t.Fatal("expected a withdrawal by another key to be rejected")
+	}
+	if fake.Calls("sendTransaction") != 0 {
+		t.Fatal("a rejected withdrawal must not be sent")
+	}
+	if _, err := actor.WithdrawFromTreasury(authority, 1000); err != nil {
+		t.Fatal(err)
+	}
+	withdraw := auction_house_types.NewWithdrawFromTreasuryInstructionBuilder()
+	tx := fake.LastSent()
+	withdraw.AccountMetaSlice = tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	if !withdraw.GetAuthorityAccount().PublicKey.Equals(authority.PublicKey()) || !withdraw.GetAuthorityAccount().IsSigner ||
+		!withdraw.GetTreasuryWithdrawalDestinationAccount().PublicKey.Equals(actor.AuctionHouseData.TreasuryWithdrawalDestination) ||
+		!withdraw.GetAuctionHouseTreasuryAccount().PublicKey.Equals(actor.AuctionHouseData.AuctionHouseTreasury) {
+		t.Fatalf("unexpected withdraw from treasury accounts %v", withdraw.AccountMetaSlice)
+	}
+}
@@ -1,7 +1,6 @@
 package auction_house
 
 import (
-	"context"
 	"encoding/binary"
 	bin "github.com/gagliardetto/binary"
 	token_metadata "github.com/gagliardetto/metaplex-go/clients/token-metadata"
@@ -13,10 +12,11 @@ import (
 )
 
 func NewAuctionHouseActor(wm *wallet_manager.WalletManager, auctionHouseAccount solana.PublicKey) (*AuctionHouseActor, error) {
-	aucHouseData, err := getAuctionHouseAccountData(wm.Client, auctionHouseAccount)
+	aucHouseData, err := getAuctionHouseAccountData(wm, auctionHouseAccount)
 	if err != nil {
 		return nil, errors.Errorf("failed to get auc house account data. err: %s", err.Error())
 	}
+	setBuyPolicy(wm)
 	return &AuctionHouseActor{
 		Wm:                  wm,
 		AuctionHouseAccount: auctionHouseAccount,
@@ -24,72 +24,99 @@ func NewAuctionHouseActor(wm *wallet_manager.WalletManager, auctionHouseAccount
 	}, nil
 }
 
-func (aucHouse *AuctionHouseActor) Buy(buyer solana.PrivateKey, data AuctionHouseBuyData) (solana.Signature, error) {
-	buyerEscrowAccount, buyerEscrowBump, err := aucHouse.getBuyerEscrow(buyer.PublicKey())
+// DryRun returns a copy of the actor whose operations are simulated instead of sent.
+// Simulation results are available through Wm.Simulations.
+func (aucHouse *AuctionHouseActor) DryRun() *AuctionHouseActor {
+	dryRun := *aucHouse
+	dryRun.Wm = aucHouse.Wm.DryRun()
+	return &dryRun
+}
+
+func (aucHouse *AuctionHouseActor) Buy(buyer solana.PrivateKey, data AuctionHouseBuyData) (*wallet_manager.OperationResult, error) {
+	pay, err := aucHouse.newPayment(buyer.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	buyInstruction, err := aucHouse.makeBuyInstruction(buyer.PublicKey(), pay, data.MintAta, data.MintAddress, data.Price, data.TokenSize)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
-	buyerTradeStateAccount, buyerTradeStateBump, err := aucHouse.getTradeState(
+	executeSaleInstruction, err := aucHouse.makeExecuteSaleInstruction(executeSaleData{
+		Buyer:           buyer.PublicKey(),
+		Seller:          data.Owner,
+		TokenAccount:    data.MintAta,
+		Mint:            data.MintAddress,
+		BuyerTradeState: buyInstruction.GetBuyerTradeStateAccount().PublicKey,
+		Price:           data.Price,
+		TokenSize:       data.TokenSize,
+		Creators:        data.Creators,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aucHouse.send(
 		buyer.PublicKey(),
-		data.MintAta,
-		data.MintAddress,
-		data.Price,
-		data.TokenSize,
+		wallet_manager.AppendMemo(
+			append(pay.wrap(buyer.PublicKey(), data.Price, buyInstruction.Build()), executeSaleInstruction),
+			data.Memo,
+			data.SignMemo,
+			buyer.PublicKey(),
+		),
+		pay.signers(buyer),
 	)
+}
+
+type executeSaleData struct {
+	Buyer           solana.PublicKey
+	Seller          solana.PublicKey
+	TokenAccount    solana.PublicKey
+	Mint            solana.PublicKey
+	BuyerTradeState solana.PublicKey
+	Price           uint64
+	TokenSize       uint64
+	Creators        []solana.PublicKey
+}
+
+func (aucHouse *AuctionHouseActor) makeExecuteSaleInstruction(data executeSaleData) (solana.Instruction, error) {
+	buyerEscrowAccount, buyerEscrowBump, err := aucHouse.GetBuyerEscrow(data.Buyer)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
-	tokenWallet, err := getTokenWallet(buyer.PublicKey(), data.MintAddress)
+	tokenWallet, err := getTokenWallet(data.Buyer, data.Mint)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
-	metadata, err := getMetadata(data.MintAddress)
+	metadata, err := getMetadata(data.Mint)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
-	buyInstruction := auction_house_types.NewBuyInstructionBuilder().
-		SetTradeStateBump(buyerTradeStateBump).
-		SetEscrowPaymentBump(buyerEscrowBump).
-		SetBuyerPrice(data.Price).
-		SetTokenSize(data.TokenSize).
-		SetWalletAccount(buyer.PublicKey()).
-		SetPaymentAccountAccount(buyer.PublicKey()).
-		SetTransferAuthorityAccount(solana.SystemProgramID).
-		SetMetadataAccount(metadata).
-		SetTokenAccountAccount(data.MintAta).
-		SetEscrowPaymentAccountAccount(buyerEscrowAccount).
-		SetTreasuryMintAccount(aucHouse.AuctionHouseData.TreasuryMint).
-		SetAuthorityAccount(aucHouse.AuctionHouseData.Authority).
-		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
-		SetAuctionHouseFeeAccountAccount(aucHouse.AuctionHouseData.AuctionHouseFeeAccount).
-		SetBuyerTradeStateAccount(buyerTradeStateAccount).
-		SetTokenProgramAccount(solana.TokenProgramID).
-		SetSystemProgramAccount(solana.SystemProgramID).
-		SetRentAccount(solana.SysVarRentPubkey).
-		Build()
 	sellerTradeStateAccount, _, err := aucHouse.getTradeState(
-		data.Owner,
-		data.MintAta,
-		data.MintAddress,
+		data.Seller,
+		data.TokenAccount,
+		data.Mint,
 		data.Price,
 		data.TokenSize,
 	)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
 	freeTradeStateAccount, freeTradeStateAccountBump, err := aucHouse.getTradeState(
-		data.Owner,
-		data.MintAta,
-		data.MintAddress,
+		data.Seller,
+		data.TokenAccount,
+		data.Mint,
 		0,
 		data.TokenSize,
 	)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
 	programAsSignerAccount, programAsSignerBump, err := getProgramAsSigner()
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
+	}
+	sellerPaymentAccount, err := aucHouse.paymentAccount(data.Seller)
+	if err != nil {
+		return nil, err
 	}
 	executeSaleInstructionBuilder := auction_house_types.NewExecuteSaleInstructionBuilder().
 		SetEscrowPaymentBump(buyerEscrowBump).
@@ -97,36 +124,81 @@ func (aucHouse *AuctionHouseActor) Buy(buyer solana.PrivateKey, data AuctionHous
 		SetProgramAsSignerBump(programAsSignerBump).
 		SetBuyerPrice(data.Price).
 		SetTokenSize(data.TokenSize).
-		SetBuyerAccount(buyer.PublicKey()).
-		SetSellerAccount(data.Owner).
+		SetBuyerAccount(data.Buyer).
+		SetSellerAccount(data.Seller).
 		SetMetadataAccount(metadata).
-		SetTokenAccountAccount(data.MintAta).
-		SetTokenMintAccount(data.MintAddress).
+		SetTokenAccountAccount(data.TokenAccount).
+		SetTokenMintAccount(data.Mint).
 		SetEscrowPaymentAccountAccount(buyerEscrowAccount).
 		SetTreasuryMintAccount(aucHouse.AuctionHouseData.TreasuryMint).
-		SetSellerPaymentReceiptAccountAccount(data.Owner).
+		SetSellerPaymentReceiptAccountAccount(sellerPaymentAccount).
 		SetBuyerReceiptTokenAccountAccount(tokenWallet).
 		SetAuthorityAccount(aucHouse.AuctionHouseData.Authority).
 		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
 		SetAuctionHouseFeeAccountAccount(aucHouse.AuctionHouseData.AuctionHouseFeeAccount).
 		SetAuctionHouseTreasuryAccount(aucHouse.AuctionHouseData.AuctionHouseTreasury).
 		SetSellerTradeStateAccount(sellerTradeStateAccount).
-		SetBuyerTradeStateAccount(buyerTradeStateAccount).
+		SetBuyerTradeStateAccount(data.BuyerTradeState).
 		SetTokenProgramAccount(solana.TokenProgramID).
 		SetSystemProgramAccount(solana.SystemProgramID).
 		SetAtaProgramAccount(solana.SPLAssociatedTokenAccountProgramID).
 		SetProgramAsSignerAccount(programAsSignerAccount).
 		SetRentAccount(solana.SysVarRentPubkey).
 		SetFreeTradeStateAccount(freeTradeStateAccount)
+	executeSaleInstructionBuilder.GetAuthorityAccount().IsSigner = aucHouse.authoritySigns()
 
-	for _, creator := range data.Creators {
+	creators, err := aucHouse.resolveCreatorAccounts(data.Mint, data.Creators)
+	if err != nil {
+		return nil, err
+	}
+	for _, creator := range creators {
 		executeSaleInstructionBuilder.Append(solana.NewAccountMeta(creator, true, false))
 	}
-	return aucHouse.Wm.SendAndConfirmInstructions(
-		buyer.PublicKey(),
-		[]solana.Instruction{buyInstruction, executeSaleInstructionBuilder.Build()},
-		[]solana.PrivateKey{buyer},
-	)
+	return executeSaleInstructionBuilder.Build(), nil
+}
+
+func (aucHouse *AuctionHouseActor) makeBuyInstruction(
+	buyer solana.PublicKey,
+	pay payment,
+	tokenAccount,
+	mint solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+) (*auction_house_types.Buy, error) {
+	buyerEscrowAccount, buyerEscrowBump, err := aucHouse.GetBuyerEscrow(buyer)
+	if err != nil {
+		return nil, err
+	}
+	buyerTradeStateAccount, buyerTradeStateBump, err := aucHouse.getTradeState(buyer, tokenAccount, mint, price, tokenSize)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := getMetadata(mint)
+	if err != nil {
+		return nil, err
+	}
+	buyInstruction := auction_house_types.NewBuyInstructionBuilder().
+		SetTradeStateBump(buyerTradeStateBump).
+		SetEscrowPaymentBump(buyerEscrowBump).
+		SetBuyerPrice(price).
+		SetTokenSize(tokenSize).
+		SetWalletAccount(buyer).
+		SetPaymentAccountAccount(pay.account).
+		SetTransferAuthorityAccount(pay.authority).
+		SetMetadataAccount(metadata).
+		SetTokenAccountAccount(tokenAccount).
+		SetEscrowPaymentAccountAccount(buyerEscrowAccount).
+		SetTreasuryMintAccount(aucHouse.AuctionHouseData.TreasuryMint).
+		SetAuthorityAccount(aucHouse.AuctionHouseData.Authority).
+		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
+		SetAuctionHouseFeeAccountAccount(aucHouse.AuctionHouseData.AuctionHouseFeeAccount).
+		SetBuyerTradeStateAccount(buyerTradeStateAccount).
+		SetTokenProgramAccount(solana.TokenProgramID).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		SetRentAccount(solana.SysVarRentPubkey)
+	buyInstruction.GetTransferAuthorityAccount().IsSigner = pay.signsTransfer()
+	buyInstruction.GetAuthorityAccount().IsSigner = aucHouse.authoritySigns()
+	return buyInstruction, nil
 }
 
 func (aucHouse *AuctionHouseActor) Sell(
@@ -134,36 +206,65 @@ func (aucHouse *AuctionHouseActor) Sell(
 	mint solana.PublicKey,
 	priceLamports uint64,
 	amount uint64,
-) (solana.Signature, error) {
-	meta, err := getMetadata(mint)
+) (*wallet_manager.OperationResult, error) {
+	return aucHouse.SellWithData(seller, AuctionHouseSellData{
+		MintAddress: mint,
+		Price:       priceLamports,
+		TokenSize:   amount,
+	})
+}
+
+func (aucHouse *AuctionHouseActor) SellWithData(
+	seller solana.PrivateKey,
+	data AuctionHouseSellData,
+) (*wallet_manager.OperationResult, error) {
+	mintAta, _, err := solana.FindAssociatedTokenAddress(seller.PublicKey(), data.MintAddress)
+	if err != nil {
+		return nil, err
+	}
+	instruction, err := aucHouse.makeSellInstruction(seller.PublicKey(), mintAta, data.MintAddress, data.Price, data.TokenSize)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
-	mintAta, _, err := solana.FindAssociatedTokenAddress(seller.PublicKey(), mint)
+	return aucHouse.send(
+		seller.PublicKey(),
+		wallet_manager.AppendMemo([]solana.Instruction{instruction.Build()}, data.Memo, data.SignMemo, seller.PublicKey()),
+		[]solana.PrivateKey{seller},
+	)
+}
+
+func (aucHouse *AuctionHouseActor) makeSellInstruction(
+	seller,
+	tokenAccount,
+	mint solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+) (*auction_house_types.Sell, error) {
+	meta, err := getMetadata(mint)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
 	programAsSigner, programAsSignerBump, err := getProgramAsSigner()
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
-	tradeState, tradeBump, err := aucHouse.getTradeState(seller.PublicKey(), mintAta, mint, priceLamports, amount)
+	tradeState, tradeBump, err := aucHouse.getTradeState(seller, tokenAccount, mint, price, tokenSize)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
-	freeTradeState, freeTradeBump, err := aucHouse.getTradeState(seller.PublicKey(), mintAta, mint, 0, amount)
+	freeTradeState, freeTradeBump, err := aucHouse.getTradeState(seller, tokenAccount, mint, 0, tokenSize)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
-	instruction := auction_house_types.NewSellInstructionBuilder().
+	sellInstruction := auction_house_types.NewSellInstructionBuilder().
 		SetTradeStateBump(tradeBump).
 		SetFreeTradeStateBump(freeTradeBump).
 		SetProgramAsSignerBump(programAsSignerBump).
-		SetBuyerPrice(priceLamports).
-		SetTokenSize(amount).
-		SetWalletAccount(seller.PublicKey()).
+		SetBuyerPrice(price).
+		SetTokenSize(tokenSize).
+		SetWalletAccount(seller).
 		SetMetadataAccount(meta).
-		SetTokenAccountAccount(mintAta).
+		SetTokenAccountAccount(tokenAccount).
 		SetAuthorityAccount(aucHouse.AuctionHouseData.Authority).
 		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
 		SetAuctionHouseFeeAccountAccount(aucHouse.AuctionHouseData.AuctionHouseFeeAccount).
@@ -172,12 +273,12 @@ func (aucHouse *AuctionHouseActor) Sell(
 		SetTokenProgramAccount(solana.TokenProgramID).
 		SetSystemProgramAccount(solana.SystemProgramID).
 		SetProgramAsSignerAccount(programAsSigner).
-		SetRentAccount(solana.SysVarRentPubkey).
-		Build()
-	return aucHouse.Wm.SendAndConfirmInstructions(seller.PublicKey(), []solana.Instruction{instruction}, []solana.PrivateKey{seller})
+		SetRentAccount(solana.SysVarRentPubkey)
+	sellInstruction.GetAuthorityAccount().IsSigner = aucHouse.authoritySigns()
+	return sellInstruction, nil
 }
 
-func (aucHouse *AuctionHouseActor) getBuyerEscrow(wallet solana.PublicKey) (solana.PublicKey, uint8, error) {
+func (aucHouse *AuctionHouseActor) GetBuyerEscrow(wallet solana.PublicKey) (solana.PublicKey, uint8, error) {
 	return solana.FindProgramAddress(
 		[][]byte{[]byte(auctionHouse), aucHouse.AuctionHouseAccount.Bytes(), wallet.Bytes()},
 		auction_house_types.ProgramID,
@@ -210,6 +311,10 @@ func (aucHouse *AuctionHouseActor) getTradeState(
 	)
 }
 
+func (aucHouse *AuctionHouseActor) isNativeTreasury() bool {
+	return aucHouse.AuctionHouseData.TreasuryMint.Equals(solana.SolMint)
+}
+
 func getTokenWallet(wallet solana.PublicKey, mint solana.PublicKey) (solana.PublicKey, error) {
 	addr, _, err := solana.FindProgramAddress(
 		[][]byte{
@@ -234,8 +339,54 @@ func getMetadata(mint solana.PublicKey) (solana.PublicKey, error) {
 	return addr, err
 }
 
-func getAuctionHouseAccountData(client *rpc.Client, auctionHouseAccountKey solana.PublicKey) (auction_house_types.AuctionHouse, error) {
-	candyMachineRaw, err := client.GetAccountInfo(context.TODO(), auctionHouseAccountKey)
+func getListingReceipt(tradeState solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress(
+		[][]byte{[]byte(listingReceipt), tradeState.Bytes()},
+		auction_house_types.ProgramID,
+	)
+}
+
+func getBidReceipt(tradeState solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress(
+		[][]byte{[]byte(bidReceipt), tradeState.Bytes()},
+		auction_house_types.ProgramID,
+	)
+}
+
+// getAccountData decodes an auction house program account into out. It returns
+// rpc.ErrNotFound when the account does not exist.
+func getAccountData(wm *wallet_manager.WalletManager, account solana.PublicKey, out interface{}) error {
+	raw, err := wm.Client.GetAccountInfo(wm.Context, account)
+	if err != nil {
+		return err
+	}
+	return bin.NewBorshDecoder(raw.Value.Data.GetBinary()).Decode(out)
+}
+
+func accountExists(wm *wallet_manager.WalletManager, account solana.PublicKey) (bool, error) {
+	_, err := wm.Client.GetAccountInfo(wm.Context, account)
+	if err == rpc.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// getMintFromMetadata reads the mint stored in a Metaplex metadata account, which
+// follows the one byte key and the update authority.
+func getMintFromMetadata(wm *wallet_manager.WalletManager, metadata solana.PublicKey) (solana.PublicKey, error) {
+	raw, err := wm.Client.GetAccountInfo(wm.Context, metadata)
+	if err != nil {
+		return solana.PublicKey{}, errors.Errorf("failed to get metadata %s. err: %s", metadata.String(), err.Error())
+	}
+	data := raw.Value.Data.GetBinary()
+	if len(data) < 65 {
+		return solana.PublicKey{}, errors.Errorf("metadata %s is too short", metadata.String())
+	}
+	return solana.PublicKeyFromBytes(data[33:65]), nil
+}
+
+func getAuctionHouseAccountData(wm *wallet_manager.WalletManager, auctionHouseAccountKey solana.PublicKey) (auction_house_types.AuctionHouse, error) {
+	candyMachineRaw, err := wm.Client.GetAccountInfo(wm.Context, auctionHouseAccountKey)
 	if err != nil {
 		return auction_house_types.AuctionHouse{}, err
 	}
@@ -248,6 +399,55 @@ func getAuctionHouseAccountData(client *rpc.Client, auctionHouseAccountKey solan
 	return aucHouse, nil
 }
 
+// searchInstructions decodes the auction house instructions of the latest
+// transactions of address, newest first, and passes them to visit until it returns
+// true. Transactions which can not be fetched or decoded are skipped.
+func (aucHouse *AuctionHouseActor) searchInstructions(
+	address solana.PublicKey,
+	visit func(instruction *auction_house_types.Instruction) (bool, error),
+) error {
+	limit := listingSearchDepth
+	signatures, err := aucHouse.Wm.Client.GetSignaturesForAddressWithOpts(aucHouse.Wm.Context, address, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: aucHouse.Wm.Commitment,
+	})
+	if err != nil {
+		return errors.Errorf("failed to get signatures of %s. err: %s", address.String(), err.Error())
+	}
+	for _, signature := range signatures {
+		if signature.Err != nil {
+			continue
+		}
+		// pruned and versioned transactions can not be loaded, they do not stop the search
+		result, err := aucHouse.Wm.Client.GetTransaction(aucHouse.Wm.Context, signature.Signature, &rpc.GetTransactionOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: aucHouse.Wm.Commitment,
+		})
+		if err != nil || result == nil || result.Transaction == nil {
+			continue
+		}
+		tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(result.Transaction.GetBinary()))
+		if err != nil {
+			continue
+		}
+		for _, instruction := range tx.Message.Instructions {
+			programID, err := tx.Message.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+			if err != nil || !programID.Equals(auction_house_types.ProgramID) {
+				continue
+			}
+			decoded, err := auction_house_types.DecodeInstruction(instruction.ResolveInstructionAccounts(&tx.Message), instruction.Data)
+			if err != nil {
+				continue
+			}
+			done, err := visit(decoded)
+			if err != nil || done {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func getProgramAsSigner() (solana.PublicKey, uint8, error) {
 	return solana.FindProgramAddress(
 		[][]byte{[]byte(auctionHouse), []byte("signer")},
@@ -18,7 +18,7 @@ func TestAuctionHouseActor_Sell(t *testing.T) {
 		t.Skip("privateKeyString or mintString is not set")
 	}
 
-	sig, err := aucHouse.Sell(
+	result, err := aucHouse.Sell(
 		solana.MustPrivateKeyFromBase58(privateKeyString),
 		solana.MustPublicKeyFromBase58(mintString),
 		uint64(0.01*float64(solana.LAMPORTS_PER_SOL)),
@@ -27,5 +27,5 @@ func TestAuctionHouseActor_Sell(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Log(sig.String())
+	t.Log(result.Signature().String())
 }
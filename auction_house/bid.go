@@ -0,0 +1,118 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+)
+
+// PlaceBid creates the buyer trade state for tokenSize tokens held in
+// data.TokenAccount at data.Price. The Buy instruction moves the missing part of
+// the price from the buyer into the escrow account; the sale is executed later by
+// the listing side. It returns the bid trade state.
+func (aucHouse *AuctionHouseActor) PlaceBid(
+	buyer solana.PrivateKey,
+	data AuctionHouseBidData,
+) (solana.PublicKey, *wallet_manager.OperationResult, error) {
+	if data.Price == 0 || data.TokenSize == 0 {
+		return solana.PublicKey{}, nil, errors.New("bid price and token size must be positive")
+	}
+	pay, err := aucHouse.newPayment(buyer.PublicKey())
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	buyInstruction, err := aucHouse.makeBuyInstruction(buyer.PublicKey(), pay, data.TokenAccount, data.MintAddress, data.Price, data.TokenSize)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	tradeState := buyInstruction.GetBuyerTradeStateAccount().PublicKey
+	instructions := []solana.Instruction{buyInstruction.Build()}
+	if data.PrintReceipt {
+		receiptInstruction, err := makePrintBidReceiptInstruction(buyer.PublicKey(), tradeState)
+		if err != nil {
+			return solana.PublicKey{}, nil, err
+		}
+		instructions = append(instructions, receiptInstruction)
+	}
+	instructions = pay.wrap(buyer.PublicKey(), data.Price, instructions...)
+	result, err := aucHouse.send(
+		buyer.PublicKey(),
+		wallet_manager.AppendMemo(instructions, data.Memo, data.SignMemo, buyer.PublicKey()),
+		pay.signers(buyer),
+	)
+	return tradeState, result, err
+}
+
+// makePrintBidReceiptInstruction has to directly follow the buy instruction it
+// prints the receipt for.
+func makePrintBidReceiptInstruction(bookkeeper, tradeState solana.PublicKey) (solana.Instruction, error) {
+	receipt, receiptBump, err := getBidReceipt(tradeState)
+	if err != nil {
+		return nil, err
+	}
+	return auction_house_types.NewPrintBidReceiptInstructionBuilder().
+		SetReceiptBump(receiptBump).
+		SetReceiptAccount(receipt).
+		SetBookkeeperAccount(bookkeeper).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		SetRentAccount(solana.SysVarRentPubkey).
+		SetInstructionAccount(solana.SysVarInstructionsPubkey).
+		Build(), nil
+}
+
+// AcceptBid lists the seller's tokens at the bid price and executes the sale
+// against the bid of data.Buyer in one transaction. Private bids must have been
+// placed on the seller's associated token account.
+func (aucHouse *AuctionHouseActor) AcceptBid(
+	seller solana.PrivateKey,
+	data AuctionHouseAcceptBidData,
+) (*wallet_manager.OperationResult, error) {
+	mintAta, _, err := solana.FindAssociatedTokenAddress(seller.PublicKey(), data.MintAddress)
+	if err != nil {
+		return nil, err
+	}
+	var buyerTradeState solana.PublicKey
+	if data.Public {
+		buyerTradeState, _, err = aucHouse.getPublicTradeState(data.Buyer, data.MintAddress, data.Price, data.TokenSize)
+	} else {
+		buyerTradeState, _, err = aucHouse.getTradeState(data.Buyer, mintAta, data.MintAddress, data.Price, data.TokenSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	exists, err := accountExists(aucHouse.Wm, buyerTradeState)
+	if err != nil {
+		return nil, errors.Errorf("failed to get bid trade state %s. err: %s", buyerTradeState.String(), err.Error())
+	}
+	if !exists {
+		return nil, errors.Wrap(ErrTradeStateClosed, buyerTradeState.String())
+	}
+	sellInstruction, err := aucHouse.makeSellInstruction(seller.PublicKey(), mintAta, data.MintAddress, data.Price, data.TokenSize)
+	if err != nil {
+		return nil, err
+	}
+	executeSaleInstruction, err := aucHouse.makeExecuteSaleInstruction(executeSaleData{
+		Buyer:           data.Buyer,
+		Seller:          seller.PublicKey(),
+		TokenAccount:    mintAta,
+		Mint:            data.MintAddress,
+		BuyerTradeState: buyerTradeState,
+		Price:           data.Price,
+		TokenSize:       data.TokenSize,
+		Creators:        data.Creators,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aucHouse.send(
+		seller.PublicKey(),
+		wallet_manager.AppendMemo(
+			[]solana.Instruction{sellInstruction.Build(), executeSaleInstruction},
+			data.Memo,
+			data.SignMemo,
+			seller.PublicKey(),
+		),
+		[]solana.PrivateKey{seller},
+	)
+}
@@ -0,0 +1,42 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/auction_house/auction_house_types"
+	"testing"
+)
+
+func TestAuctionHouseActor_PlaceBid(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:    solana.NewWallet().PublicKey(),
+		TreasuryMint: solana.SolMint,
+	})
+	buyer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	tokenAccount := solana.NewWallet().PublicKey()
+
+	tradeState, _, err := actor.PlaceBid(buyer, AuctionHouseBidData{
+		MintAddress:  mint,
+		TokenAccount: tokenAccount,
+		Price:        1000,
+		TokenSize:    1,
+		PrintReceipt: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _, _ := actor.getTradeState(buyer.PublicKey(), tokenAccount, mint, 1000, 1)
+	if !tradeState.Equals(expected) {
+		t.Fatalf("expected trade state %s, got %s", expected, tradeState)
+	}
+	tx := fake.LastSent()
+	if len(tx.Message.Instructions) != 2 {
+		t.Fatalf("expected buy and print receipt instructions, got %d", len(tx.Message.Instructions))
+	}
+	receipt, _, _ := getBidReceipt(tradeState)
+	accounts := tx.Message.Instructions[1].ResolveInstructionAccounts(&tx.Message)
+	if !accounts[0].PublicKey.Equals(receipt) || !accounts[1].PublicKey.Equals(buyer.PublicKey()) {
+		t.Fatalf("unexpected print receipt accounts %v", accounts)
+	}
+}
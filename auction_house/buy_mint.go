@@ -0,0 +1,159 @@
+package auction_house
+
+import (
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+)
+
+const (
+	listingReceiptAuctionHouseOffset = 8 + 32*2
+	listingReceiptSellerOffset       = 8 + 32*3
+	listingReceiptMetadataOffset     = 8 + 32*4
+	listingSearchDepth               = 20
+)
+
+// BuyMint buys mint from its current holder when it is listed on the auction
+// house for at most maxPrice.
+func (aucHouse *AuctionHouseActor) BuyMint(
+	buyer solana.PrivateKey,
+	mint solana.PublicKey,
+	maxPrice uint64,
+) (AuctionHouseListing, *wallet_manager.OperationResult, error) {
+	listing, err := aucHouse.FindListing(mint)
+	if err != nil {
+		return AuctionHouseListing{}, nil, err
+	}
+	if listing.Price > maxPrice {
+		return listing, nil, errors.Errorf("%s is listed for %d, more than %d", mint.String(), listing.Price, maxPrice)
+	}
+	result, err := aucHouse.Buy(buyer, AuctionHouseBuyData{
+		Owner:       listing.Seller,
+		MintAddress: mint,
+		MintAta:     listing.TokenAccount,
+		Price:       listing.Price,
+		TokenSize:   listing.TokenSize,
+	})
+	return listing, result, err
+}
+
+// FindListing returns the active listing of mint by its current holder. The
+// cheapest listing receipt is preferred; without one the holder's recent Sell
+// transactions are searched for a trade state which is still open.
+func (aucHouse *AuctionHouseActor) FindListing(mint solana.PublicKey) (AuctionHouseListing, error) {
+	tokenAccount, seller, err := aucHouse.findTokenHolder(mint)
+	if err != nil {
+		return AuctionHouseListing{}, err
+	}
+	listing, found, err := aucHouse.findListingByReceipt(mint, tokenAccount, seller)
+	if err != nil || found {
+		return listing, err
+	}
+	listing, found, err = aucHouse.findListingBySellTransaction(mint, tokenAccount, seller)
+	if err != nil || found {
+		return listing, err
+	}
+	return AuctionHouseListing{}, errors.Errorf("%s is not listed on auction house %s", mint.String(), aucHouse.AuctionHouseAccount.String())
+}
+
+func (aucHouse *AuctionHouseActor) findListingByReceipt(
+	mint,
+	tokenAccount,
+	seller solana.PublicKey,
+) (AuctionHouseListing, bool, error) {
+	metadata, err := getMetadata(mint)
+	if err != nil {
+		return AuctionHouseListing{}, false, err
+	}
+	accounts, err := aucHouse.Wm.Client.GetProgramAccountsWithOpts(aucHouse.Wm.Context, auction_house_types.ProgramID, &rpc.GetProgramAccountsOpts{
+		Commitment: aucHouse.Wm.Commitment,
+		Encoding:   solana.EncodingBase64,
+		Filters: []rpc.RPCFilter{
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: auction_house_types.ListingReceiptDiscriminator[:]}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: listingReceiptAuctionHouseOffset, Bytes: aucHouse.AuctionHouseAccount.Bytes()}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: listingReceiptSellerOffset, Bytes: seller.Bytes()}},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: listingReceiptMetadataOffset, Bytes: metadata.Bytes()}},
+		},
+	})
+	if err != nil {
+		return AuctionHouseListing{}, false, errors.Errorf("failed to get listing receipts of %s. err: %s", mint.String(), err.Error())
+	}
+	var best AuctionHouseListing
+	found := false
+	for _, account := range accounts {
+		var receipt auction_house_types.ListingReceipt
+		if err = bin.NewBorshDecoder(account.Account.Data.GetBinary()).Decode(&receipt); err != nil {
+			return AuctionHouseListing{}, false, errors.Errorf("failed to decode listing receipt %s. err: %s", account.Pubkey.String(), err.Error())
+		}
+		if receipt.CanceledAt != nil || receipt.PurchaseReceipt != nil || (found && receipt.Price >= best.Price) {
+			continue
+		}
+		// Receipts of listings made from an older token account do not match.
+		tradeState, _, err := aucHouse.getTradeState(seller, tokenAccount, mint, receipt.Price, receipt.TokenSize)
+		if err != nil {
+			return AuctionHouseListing{}, false, err
+		}
+		if !tradeState.Equals(receipt.TradeState) {
+			continue
+		}
+		open, err := accountExists(aucHouse.Wm, tradeState)
+		if err != nil {
+			return AuctionHouseListing{}, false, err
+		}
+		if !open {
+			continue
+		}
+		best = AuctionHouseListing{
+			Receipt:      account.Pubkey,
+			TradeState:   tradeState,
+			Seller:       seller,
+			TokenAccount: tokenAccount,
+			Price:        receipt.Price,
+			TokenSize:    receipt.TokenSize,
+		}
+		found = true
+	}
+	return best, found, nil
+}
+
+func (aucHouse *AuctionHouseActor) findListingBySellTransaction(
+	mint,
+	tokenAccount,
+	seller solana.PublicKey,
+) (AuctionHouseListing, bool, error) {
+	var listing AuctionHouseListing
+	found := false
+	err := aucHouse.searchInstructions(tokenAccount, func(instruction *auction_house_types.Instruction) (bool, error) {
+		sell, ok := instruction.Impl.(*auction_house_types.Sell)
+		if !ok ||
+			!sell.GetAuctionHouseAccount().PublicKey.Equals(aucHouse.AuctionHouseAccount) ||
+			!sell.GetWalletAccount().PublicKey.Equals(seller) ||
+			!sell.GetTokenAccountAccount().PublicKey.Equals(tokenAccount) {
+			return false, nil
+		}
+		tradeState, _, err := aucHouse.getTradeState(seller, tokenAccount, mint, *sell.BuyerPrice, *sell.TokenSize)
+		if err != nil {
+			return false, err
+		}
+		open, err := accountExists(aucHouse.Wm, tradeState)
+		if err != nil || !open {
+			return false, err
+		}
+		listing = AuctionHouseListing{
+			TradeState:   tradeState,
+			Seller:       seller,
+			TokenAccount: tokenAccount,
+			Price:        *sell.BuyerPrice,
+			TokenSize:    *sell.TokenSize,
+		}
+		found = true
+		return true, nil
+	})
+	if err != nil {
+		return AuctionHouseListing{}, false, err
+	}
+	return listing, found, nil
+}
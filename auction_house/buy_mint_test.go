@@ -0,0 +1,129 @@
+package auction_house
+
+import (
+	token_metadata "github.com/gagliardetto/metaplex-go/clients/token-metadata"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+	"testing"
+)
+
+func newListedMint(t *testing.T) (*fakeCluster, *AuctionHouseActor, solana.PrivateKey, solana.PublicKey, solana.PublicKey) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:    solana.NewWallet().PublicKey(),
+		TreasuryMint: solana.SolMint,
+	})
+	seller := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	tokenAccount := solana.NewWallet().PublicKey()
+	fake.SetTokenAccount(tokenAccount, mint, seller.PublicKey(), 1)
+	metadata, _ := getMetadata(mint)
+	fake.setMetadata(metadata, token_metadata.Metadata{Mint: mint})
+	return fake, actor, seller, mint, tokenAccount
+}
+
+func TestAuctionHouseActor_BuyMintByReceipt(t *testing.T) {
+	fake, actor, seller, mint, tokenAccount := newListedMint(t)
+	metadata, _ := getMetadata(mint)
+	addListing := func(price uint64, canceled bool) {
+		tradeState, _, _ := actor.getTradeState(seller.PublicKey(), tokenAccount, mint, price, 1)
+		fake.SetAccount(tradeState, auction_house_types.ProgramID, 1, []byte{255})
+		receipt, _, _ := getListingReceipt(tradeState)
+		data := auction_house_types.ListingReceipt{
+			TradeState:   tradeState,
+			AuctionHouse: actor.AuctionHouseAccount,
+			Seller:       seller.PublicKey(),
+			Metadata:     metadata,
+			Price:        price,
+			TokenSize:    1,
+		}
+		if canceled {
+			canceledAt := int64(1)
+			data.CanceledAt = &canceledAt
+		}
+		fake.setProgramAccount(receipt, &data)
+	}
+	addListing(500, true)
+	addListing(900, false)
+
+	buyer := solana.NewWallet().PrivateKey
+	if _, _, err := actor.BuyMint(buyer, mint, 800); err == nil {
+		t.Fatal("expected listing above max price to be rejected")
+	}
+	listing, _, err := actor.BuyMint(buyer, mint, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listing.Price != 900 || listing.Receipt.IsZero() || !listing.Seller.Equals(seller.PublicKey()) {
+		t.Fatalf("unexpected listing %+v", listing)
+	}
+	tx := fake.LastSent()
+	buy := auction_house_types.NewBuyInstructionBuilder()
+	buy.AccountMetaSlice = tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	if !buy.GetTokenAccountAccount().PublicKey.Equals(tokenAccount) {
+		t.Fatalf("expected token account %s, got %s", tokenAccount, buy.GetTokenAccountAccount().PublicKey)
+	}
+}
+
+func TestAuctionHouseActor_FindListingBySellTransaction(t *testing.T) {
+	fake, actor, seller, mint, tokenAccount := newListedMint(t)
+	sell, err := actor.makeSellInstruction(seller.PublicKey(), tokenAccount, mint, 600, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := solana.NewTransaction([]solana.Instruction{sell.Build()}, solana.Hash{2}, solana.TransactionPayer(seller.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wallet_manager.PartialSignTransaction(tx, []solana.PrivateKey{seller}); err != nil {
+		t.Fatal(err)
+	}
+	fake.AddHistory(tx)
+	// newer transactions which were pruned or are versioned can not be loaded
+	pruned := newSignedTransfer(t, seller, tokenAccount, 1)
+	versioned := newSignedTransfer(t, seller, tokenAccount, 2)
+	fake.AddHistory(pruned)
+	fake.AddHistory(versioned)
+	encoded, _ := tx.ToBase64()
+	fake.Handle("getTransaction", func(params []interface{}) (interface{}, error) {
+		switch params[0].(solana.Signature) {
+		case pruned.Signatures[0]:
+			return nil, nil
+		case versioned.Signatures[0]:
+			return nil, &jsonrpc.RPCError{Code: -32015, Message: "Transaction version (0) is not supported"}
+		}
+		return map[string]interface{}{
+			"slot":        1,
+			"transaction": []string{encoded, "base64"},
+			"meta":        map[string]interface{}{"err": nil, "fee": 5000, "preBalances": []uint64{}, "postBalances": []uint64{}},
+		}, nil
+	})
+
+	if _, err = actor.FindListing(mint); err == nil {
+		t.Fatal("expected closed trade state not to be found")
+	}
+	tradeState := sell.GetSellerTradeStateAccount().PublicKey
+	fake.SetAccount(tradeState, auction_house_types.ProgramID, 1, []byte{255})
+	listing, err := actor.FindListing(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listing.Price != 600 || !listing.Receipt.IsZero() || !listing.TradeState.Equals(tradeState) {
+		t.Fatalf("unexpected listing %+v", listing)
+	}
+}
+
+func newSignedTransfer(t *testing.T, from solana.PrivateKey, account solana.PublicKey, lamports uint64) *solana.Transaction {
+	transfer := system.NewTransferInstruction(lamports, from.PublicKey(), account).Build()
+	tx, err := solana.NewTransaction([]solana.Instruction{transfer}, solana.Hash{2}, solana.TransactionPayer(from.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wallet_manager.SignTransaction(tx, []solana.PrivateKey{from}); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
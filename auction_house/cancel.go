@@ -0,0 +1,247 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+)
+
+var ErrTradeStateClosed = errors.New("trade state is already closed")
+
+// CancelListing cancels the listing of tokenSize tokens of mint created by Sell at
+// price, together with its listing receipt when one was printed.
+func (aucHouse *AuctionHouseActor) CancelListing(
+	seller solana.PrivateKey,
+	mint solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+) (*wallet_manager.OperationResult, error) {
+	mintAta, _, err := solana.FindAssociatedTokenAddress(seller.PublicKey(), mint)
+	if err != nil {
+		return nil, err
+	}
+	tradeState, _, err := aucHouse.getTradeState(seller.PublicKey(), mintAta, mint, price, tokenSize)
+	if err != nil {
+		return nil, err
+	}
+	return aucHouse.cancelListing(seller, mintAta, mint, tradeState, price, tokenSize)
+}
+
+func (aucHouse *AuctionHouseActor) CancelListingByReceipt(
+	seller solana.PrivateKey,
+	receipt solana.PublicKey,
+) (*wallet_manager.OperationResult, error) {
+	var data auction_house_types.ListingReceipt
+	if err := getAccountData(aucHouse.Wm, receipt, &data); err != nil {
+		return nil, errors.Errorf("failed to get listing receipt %s. err: %s", receipt.String(), err.Error())
+	}
+	if !data.AuctionHouse.Equals(aucHouse.AuctionHouseAccount) {
+		return nil, errors.Errorf("listing receipt %s belongs to auction house %s", receipt.String(), data.AuctionHouse.String())
+	}
+	if !data.Seller.Equals(seller.PublicKey()) {
+		return nil, errors.Errorf("listing receipt %s belongs to %s", receipt.String(), data.Seller.String())
+	}
+	mint, err := getMintFromMetadata(aucHouse.Wm, data.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	tokenAccount, err := aucHouse.findListingTokenAccount(seller.PublicKey(), mint, data)
+	if err != nil {
+		return nil, err
+	}
+	return aucHouse.cancelListing(seller, tokenAccount, mint, data.TradeState, data.Price, data.TokenSize)
+}
+
+// findListingTokenAccount returns the token account a listing was made from.
+// Listing receipts do not record it, so unless the trade state derives from the
+// seller's associated token account it is read from the sell instruction which
+// created the trade state.
+func (aucHouse *AuctionHouseActor) findListingTokenAccount(
+	seller,
+	mint solana.PublicKey,
+	receipt auction_house_types.ListingReceipt,
+) (solana.PublicKey, error) {
+	mintAta, _, err := solana.FindAssociatedTokenAddress(seller, mint)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	ataTradeState, _, err := aucHouse.getTradeState(seller, mintAta, mint, receipt.Price, receipt.TokenSize)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	if ataTradeState.Equals(receipt.TradeState) {
+		return mintAta, nil
+	}
+	var tokenAccount solana.PublicKey
+	err = aucHouse.searchInstructions(receipt.TradeState, func(instruction *auction_house_types.Instruction) (bool, error) {
+		sell, ok := instruction.Impl.(*auction_house_types.Sell)
+		if !ok || !sell.GetSellerTradeStateAccount().PublicKey.Equals(receipt.TradeState) {
+			return false, nil
+		}
+		tokenAccount = sell.GetTokenAccountAccount().PublicKey
+		return true, nil
+	})
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	if tokenAccount.IsZero() {
+		return solana.PublicKey{}, errors.Errorf("sell of trade state %s not found", receipt.TradeState.String())
+	}
+	return tokenAccount, nil
+}
+
+// CancelBid cancels the bid of buyer for tokenSize tokens held in tokenAccount at
+// price, together with its bid receipt when one was printed.
+func (aucHouse *AuctionHouseActor) CancelBid(
+	buyer solana.PrivateKey,
+	mint solana.PublicKey,
+	tokenAccount solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+) (*wallet_manager.OperationResult, error) {
+	tradeState, _, err := aucHouse.getTradeState(buyer.PublicKey(), tokenAccount, mint, price, tokenSize)
+	if err != nil {
+		return nil, err
+	}
+	return aucHouse.cancelBid(buyer, tokenAccount, mint, tradeState, price, tokenSize)
+}
+
+func (aucHouse *AuctionHouseActor) CancelBidByReceipt(
+	buyer solana.PrivateKey,
+	receipt solana.PublicKey,
+) (*wallet_manager.OperationResult, error) {
+	var data auction_house_types.BidReceipt
+	if err := getAccountData(aucHouse.Wm, receipt, &data); err != nil {
+		return nil, errors.Errorf("failed to get bid receipt %s. err: %s", receipt.String(), err.Error())
+	}
+	if !data.AuctionHouse.Equals(aucHouse.AuctionHouseAccount) {
+		return nil, errors.Errorf("bid receipt %s belongs to auction house %s", receipt.String(), data.AuctionHouse.String())
+	}
+	if !data.Buyer.Equals(buyer.PublicKey()) {
+		return nil, errors.Errorf("bid receipt %s belongs to %s", receipt.String(), data.Buyer.String())
+	}
+	mint, err := getMintFromMetadata(aucHouse.Wm, data.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	var tokenAccount solana.PublicKey
+	if data.TokenAccount != nil {
+		tokenAccount = *data.TokenAccount
+	} else if tokenAccount, err = aucHouse.findPublicBidTokenAccount(data.TradeState); err != nil {
+		return nil, err
+	}
+	return aucHouse.cancelBid(buyer, tokenAccount, mint, data.TradeState, data.Price, data.TokenSize)
+}
+
+func (aucHouse *AuctionHouseActor) cancelListing(
+	seller solana.PrivateKey,
+	tokenAccount,
+	mint,
+	tradeState solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+) (*wallet_manager.OperationResult, error) {
+	receipt, _, err := getListingReceipt(tradeState)
+	if err != nil {
+		return nil, err
+	}
+	var receiptData auction_house_types.ListingReceipt
+	cancelReceipt, err := aucHouse.openReceipt(receipt, &receiptData, func() bool { return receiptData.CanceledAt == nil })
+	if err != nil {
+		return nil, err
+	}
+	var receiptInstruction solana.Instruction
+	if cancelReceipt {
+		receiptInstruction = auction_house_types.NewCancelListingReceiptInstructionBuilder().
+			SetReceiptAccount(receipt).
+			SetSystemProgramAccount(solana.SystemProgramID).
+			SetInstructionAccount(solana.SysVarInstructionsPubkey).
+			Build()
+	}
+	return aucHouse.cancel(seller, tokenAccount, mint, tradeState, price, tokenSize, receiptInstruction)
+}
+
+func (aucHouse *AuctionHouseActor) cancelBid(
+	buyer solana.PrivateKey,
+	tokenAccount,
+	mint,
+	tradeState solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+) (*wallet_manager.OperationResult, error) {
+	receipt, _, err := getBidReceipt(tradeState)
+	if err != nil {
+		return nil, err
+	}
+	var receiptData auction_house_types.BidReceipt
+	cancelReceipt, err := aucHouse.openReceipt(receipt, &receiptData, func() bool { return receiptData.CanceledAt == nil })
+	if err != nil {
+		return nil, err
+	}
+	var receiptInstruction solana.Instruction
+	if cancelReceipt {
+		receiptInstruction = auction_house_types.NewCancelBidReceiptInstructionBuilder().
+			SetReceiptAccount(receipt).
+			SetSystemProgramAccount(solana.SystemProgramID).
+			SetInstructionAccount(solana.SysVarInstructionsPubkey).
+			Build()
+	}
+	return aucHouse.cancel(buyer, tokenAccount, mint, tradeState, price, tokenSize, receiptInstruction)
+}
+
+// cancel closes tradeState. The receipt instruction, if any, has to follow the
+// cancel instruction because the program checks it through the instructions sysvar.
+func (aucHouse *AuctionHouseActor) cancel(
+	wallet solana.PrivateKey,
+	tokenAccount,
+	mint,
+	tradeState solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+	receiptInstruction solana.Instruction,
+) (*wallet_manager.OperationResult, error) {
+	exists, err := accountExists(aucHouse.Wm, tradeState)
+	if err != nil {
+		return nil, errors.Errorf("failed to get trade state %s. err: %s", tradeState.String(), err.Error())
+	}
+	if !exists {
+		return nil, errors.Wrap(ErrTradeStateClosed, tradeState.String())
+	}
+	instructions := []solana.Instruction{
+		auction_house_types.NewCancelInstructionBuilder().
+			SetBuyerPrice(price).
+			SetTokenSize(tokenSize).
+			SetWalletAccount(wallet.PublicKey()).
+			SetTokenAccountAccount(tokenAccount).
+			SetTokenMintAccount(mint).
+			SetAuthorityAccount(aucHouse.AuctionHouseData.Authority).
+			SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
+			SetAuctionHouseFeeAccountAccount(aucHouse.AuctionHouseData.AuctionHouseFeeAccount).
+			SetTradeStateAccount(tradeState).
+			SetTokenProgramAccount(solana.TokenProgramID).
+			Build(),
+	}
+	if receiptInstruction != nil {
+		instructions = append(instructions, receiptInstruction)
+	}
+	return aucHouse.Wm.SendAndConfirmInstructions(
+		wallet.PublicKey(),
+		instructions,
+		[]solana.PrivateKey{wallet},
+	)
+}
+
+// openReceipt reports whether receipt exists and isOpen holds after it has been
+// decoded into data.
+func (aucHouse *AuctionHouseActor) openReceipt(receipt solana.PublicKey, data interface{}, isOpen func() bool) (bool, error) {
+	err := getAccountData(aucHouse.Wm, receipt, data)
+	if err == rpc.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Errorf("failed to get receipt %s. err: %s", receipt.String(), err.Error())
+	}
+	return isOpen(), nil
+}
@@ -0,0 +1,128 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+	"testing"
+)
+
+func TestAuctionHouseActor_CancelListing(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{Authority: solana.NewWallet().PublicKey()})
+	seller := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+
+	_, err := actor.CancelListing(seller, mint, 100, 1)
+	if !errors.Is(err, ErrTradeStateClosed) {
+		t.Fatalf("expected closed trade state error, got %v", err)
+	}
+
+	mintAta, _, _ := solana.FindAssociatedTokenAddress(seller.PublicKey(), mint)
+	tradeState, _, _ := actor.getTradeState(seller.PublicKey(), mintAta, mint, 100, 1)
+	receipt, _, _ := getListingReceipt(tradeState)
+	fake.SetAccount(tradeState, auction_house_types.ProgramID, 1, []byte{255})
+	fake.setProgramAccount(receipt, &auction_house_types.ListingReceipt{TradeState: tradeState, Seller: seller.PublicKey()})
+
+	if _, err = actor.CancelListing(seller, mint, 100, 1); err != nil {
+		t.Fatal(err)
+	}
+	tx := fake.LastSent()
+	if len(tx.Message.Instructions) != 2 {
+		t.Fatalf("expected cancel and cancel receipt instructions, got %d", len(tx.Message.Instructions))
+	}
+	accounts := tx.Message.Instructions[1].ResolveInstructionAccounts(&tx.Message)
+	if !accounts[0].PublicKey.Equals(receipt) {
+		t.Fatalf("expected receipt %s to be cancelled, got %s", receipt, accounts[0].PublicKey)
+	}
+}
+
+func TestAuctionHouseActor_CancelListingByReceipt(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{Authority: solana.NewWallet().PublicKey()})
+	seller := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	metadata, _ := getMetadata(mint)
+	fake.SetAccount(metadata, solana.TokenMetadataProgramID, 1, append(make([]byte, 33), mint.Bytes()...))
+	// listed from a token account other than the seller's associated one
+	listedAccount := solana.NewWallet().PublicKey()
+	sell, err := actor.makeSellInstruction(seller.PublicKey(), listedAccount, mint, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := solana.NewTransaction([]solana.Instruction{sell.Build()}, solana.Hash{3}, solana.TransactionPayer(seller.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wallet_manager.SignTransaction(tx, []solana.PrivateKey{seller}); err != nil {
+		t.Fatal(err)
+	}
+	fake.AddHistory(tx)
+	tradeState := sell.GetSellerTradeStateAccount().PublicKey
+	fake.SetAccount(tradeState, auction_house_types.ProgramID, 1, []byte{255})
+	receipt, _, _ := getListingReceipt(tradeState)
+	fake.setProgramAccount(receipt, &auction_house_types.ListingReceipt{
+		TradeState:   tradeState,
+		AuctionHouse: actor.AuctionHouseAccount,
+		Seller:       seller.PublicKey(),
+		Metadata:     metadata,
+		Price:        100,
+		TokenSize:    1,
+	})
+
+	if _, err = actor.CancelListingByReceipt(seller, receipt); err != nil {
+		t.Fatal(err)
+	}
+	cancel := auction_house_types.NewCancelInstructionBuilder()
+	cancel.AccountMetaSlice = fake.LastSent().Message.Instructions[0].ResolveInstructionAccounts(&fake.LastSent().Message)
+	if !cancel.GetTokenAccountAccount().PublicKey.Equals(listedAccount) || !cancel.GetTradeStateAccount().PublicKey.Equals(tradeState) {
+		t.Fatalf("expected the listed token account %s to be cancelled, got %v", listedAccount, cancel.AccountMetaSlice)
+	}
+}
+
+func TestAuctionHouseActor_CancelBidByReceipt(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{Authority: solana.NewWallet().PublicKey()})
+	buyer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	tokenAccount := solana.NewWallet().PublicKey()
+	metadata, _ := getMetadata(mint)
+	fake.SetAccount(metadata, solana.TokenMetadataProgramID, 1, append(make([]byte, 33), mint.Bytes()...))
+
+	tradeState, _, _ := actor.getTradeState(buyer.PublicKey(), tokenAccount, mint, 500, 1)
+	fake.SetAccount(tradeState, auction_house_types.ProgramID, 1, []byte{255})
+	receipt, _, _ := getBidReceipt(tradeState)
+	canceledAt := int64(1)
+	bidReceipt := auction_house_types.BidReceipt{
+		TradeState:   tradeState,
+		AuctionHouse: solana.NewWallet().PublicKey(),
+		Buyer:        buyer.PublicKey(),
+		Metadata:     metadata,
+		TokenAccount: &tokenAccount,
+		Price:        500,
+		TokenSize:    1,
+		CanceledAt:   &canceledAt,
+	}
+	fake.setProgramAccount(receipt, &bidReceipt)
+
+	if _, err := actor.CancelBidByReceipt(buyer, receipt); err == nil {
+		t.Fatal("expected receipt of another auction house to be rejected")
+	}
+	bidReceipt.AuctionHouse = actor.AuctionHouseAccount
+	fake.setProgramAccount(receipt, &bidReceipt)
+	if _, err := actor.CancelBidByReceipt(solana.NewWallet().PrivateKey, receipt); err == nil {
+		t.Fatal("expected receipt of another buyer to be rejected")
+	}
+	if _, err := actor.CancelBidByReceipt(buyer, receipt); err != nil {
+		t.Fatal(err)
+	}
+	tx := fake.LastSent()
+	if len(tx.Message.Instructions) != 1 {
+		t.Fatalf("expected cancelled receipt to be skipped, got %d instructions", len(tx.Message.Instructions))
+	}
+	accounts := tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	if !accounts[6].PublicKey.Equals(tradeState) {
+		t.Fatalf("expected trade state %s, got %s", tradeState, accounts[6].PublicKey)
+	}
+}
@@ -3,7 +3,11 @@ package auction_house
 import "github.com/gagliardetto/solana-go"
 
 var (
-	auctionHouse = "auction_house"
+	auctionHouse   = "auction_house"
+	feePayer       = "fee_payer"
+	treasury       = "treasury"
+	listingReceipt = "listing_receipt"
+	bidReceipt     = "bid_receipt"
 
 	CoralCubeAuctionHouseAccount = solana.MustPublicKeyFromBase58("29xtkHHFLUHXiLoxTzbC7U8kekTwN3mVQSkfXnB1sQ6e")
 	OpenSeaAuctionHouseAccount   = solana.MustPublicKeyFromBase58("3o9d13qUvEuuauhFrVom1vuCzgNsJifeaBYDPquaT73Y")
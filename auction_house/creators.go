@@ -0,0 +1,84 @@
+package auction_house
+
+import (
+	token_metadata "github.com/gagliardetto/metaplex-go/clients/token-metadata"
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+)
+
+// GetRoyaltyCreators returns the creators of mint in metadata order after checking
+// that their shares add up to 100 percent.
+func (aucHouse *AuctionHouseActor) GetRoyaltyCreators(mint solana.PublicKey) ([]token_metadata.Creator, error) {
+	metadata, err := aucHouse.getMetadataData(mint)
+	if err != nil {
+		return nil, err
+	}
+	if !metadata.Mint.Equals(mint) {
+		return nil, errors.Errorf("metadata of %s belongs to mint %s", mint.String(), metadata.Mint.String())
+	}
+	if metadata.Data.SellerFeeBasisPoints > 10000 {
+		return nil, errors.Errorf("invalid seller fee basis points %d of %s", metadata.Data.SellerFeeBasisPoints, mint.String())
+	}
+	if metadata.Data.Creators == nil {
+		return nil, nil
+	}
+	creators := *metadata.Data.Creators
+	var total uint64
+	seen := map[solana.PublicKey]bool{}
+	for _, creator := range creators {
+		if seen[creator.Address] {
+			return nil, errors.Errorf("creator %s of %s is listed twice", creator.Address.String(), mint.String())
+		}
+		seen[creator.Address] = true
+		total += uint64(creator.Share)
+	}
+	if len(creators) > 0 && total != 100 {
+		return nil, errors.Errorf("creator shares of %s add up to %d instead of 100", mint.String(), total)
+	}
+	return creators, nil
+}
+
+// resolveCreatorAccounts returns the remaining accounts ExecuteSale pays royalties
+// to: every creator for native SOL houses and every creator followed by its
+// treasury mint ATA for SPL-token houses. Creators given by the caller have to
+// match the metadata.
+func (aucHouse *AuctionHouseActor) resolveCreatorAccounts(mint solana.PublicKey, given []solana.PublicKey) ([]solana.PublicKey, error) {
+	creators, err := aucHouse.GetRoyaltyCreators(mint)
+	if err != nil {
+		return nil, err
+	}
+	if len(given) > 0 {
+		if len(given) != len(creators) {
+			return nil, errors.Errorf("expected %d creators of %s, got %d", len(creators), mint.String(), len(given))
+		}
+		for idx, creator := range creators {
+			if !given[idx].Equals(creator.Address) {
+				return nil, errors.Errorf("creator %d of %s is %s, got %s", idx, mint.String(), creator.Address.String(), given[idx].String())
+			}
+		}
+	}
+	var accounts []solana.PublicKey
+	for _, creator := range creators {
+		accounts = append(accounts, creator.Address)
+		if !aucHouse.isNativeTreasury() {
+			ata, _, err := solana.FindAssociatedTokenAddress(creator.Address, aucHouse.AuctionHouseData.TreasuryMint)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, ata)
+		}
+	}
+	return accounts, nil
+}
+
+func (aucHouse *AuctionHouseActor) getMetadataData(mint solana.PublicKey) (token_metadata.Metadata, error) {
+	address, err := getMetadata(mint)
+	if err != nil {
+		return token_metadata.Metadata{}, err
+	}
+	var metadata token_metadata.Metadata
+	if err = getAccountData(aucHouse.Wm, address, &metadata); err != nil {
+		return token_metadata.Metadata{}, errors.Errorf("failed to get metadata of %s. err: %s", mint.String(), err.Error())
+	}
+	return metadata, nil
+}
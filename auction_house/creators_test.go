@@ -0,0 +1,47 @@
+package auction_house
+
+import (
+	token_metadata "github.com/gagliardetto/metaplex-go/clients/token-metadata"
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/auction_house/auction_house_types"
+	"testing"
+)
+
+func TestAuctionHouseActor_ResolveCreatorAccounts(t *testing.T) {
+	fake := newFakeCluster()
+	usdc := solana.NewWallet().PublicKey()
+	native := fake.actor(auction_house_types.AuctionHouse{TreasuryMint: solana.SolMint})
+	spl := fake.actor(auction_house_types.AuctionHouse{TreasuryMint: usdc})
+	first := solana.NewWallet().PublicKey()
+	second := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	metadata, _ := getMetadata(mint)
+	setCreators := func(creators ...token_metadata.Creator) {
+		fake.setMetadata(metadata, token_metadata.Metadata{Mint: mint, Data: token_metadata.Data{Creators: &creators}})
+	}
+
+	setCreators(token_metadata.Creator{Address: first, Share: 70}, token_metadata.Creator{Address: second, Share: 30})
+	accounts, err := native.resolveCreatorAccounts(mint, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || !accounts[0].Equals(first) || !accounts[1].Equals(second) {
+		t.Fatalf("unexpected native creator accounts %v", accounts)
+	}
+	accounts, err = spl.resolveCreatorAccounts(mint, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstAta, _, _ := solana.FindAssociatedTokenAddress(first, usdc)
+	if len(accounts) != 4 || !accounts[0].Equals(first) || !accounts[1].Equals(firstAta) {
+		t.Fatalf("unexpected spl creator accounts %v", accounts)
+	}
+	if _, err = native.resolveCreatorAccounts(mint, []solana.PublicKey{second, first}); err == nil {
+		t.Fatal("expected creators in a wrong order to be rejected")
+	}
+
+	setCreators(token_metadata.Creator{Address: first, Share: 70}, token_metadata.Creator{Address: second, Share: 20})
+	if _, err = native.resolveCreatorAccounts(mint, nil); err == nil {
+		t.Fatal("expected shares not adding up to 100 to be rejected")
+	}
+}
@@ -0,0 +1,194 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+)
+
+// KnownAuctionHouseAccounts returns the auction houses SweepEscrows checks by default.
+func KnownAuctionHouseAccounts() []solana.PublicKey {
+	return []solana.PublicKey{
+		CoralCubeAuctionHouseAccount,
+		OpenSeaAuctionHouseAccount,
+		SolanartAuctionHouseAccount,
+	}
+}
+
+type EscrowSweep struct {
+	AuctionHouse solana.PublicKey
+	Escrow       solana.PublicKey
+	Amount       uint64
+	Result       *wallet_manager.OperationResult
+	Err          error
+}
+
+// GetEscrowBalance returns the escrow balance of wallet in lamports, or in base
+// units of the treasury mint for SPL-token auction houses.
+func (aucHouse *AuctionHouseActor) GetEscrowBalance(wallet solana.PublicKey) (uint64, error) {
+	escrow, _, err := aucHouse.GetBuyerEscrow(wallet)
+	if err != nil {
+		return 0, err
+	}
+	if !aucHouse.isNativeTreasury() {
+		return aucHouse.tokenBalance(escrow)
+	}
+	balance, err := aucHouse.Wm.Client.GetBalance(aucHouse.Wm.Context, escrow, aucHouse.Wm.Commitment)
+	if err != nil {
+		return 0, errors.Errorf("failed to get escrow balance of %s. err: %s", wallet.String(), err.Error())
+	}
+	return balance.Value, nil
+}
+
+func (aucHouse *AuctionHouseActor) Deposit(wallet solana.PrivateKey, amount uint64) (*wallet_manager.OperationResult, error) {
+	escrow, escrowBump, err := aucHouse.GetBuyerEscrow(wallet.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	pay, err := aucHouse.newPayment(wallet.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	instruction := auction_house_types.NewDepositInstructionBuilder().
+		SetEscrowPaymentBump(escrowBump).
+		SetAmount(amount).
+		SetWalletAccount(wallet.PublicKey()).
+		SetPaymentAccountAccount(pay.account).
+		SetTransferAuthorityAccount(pay.authority).
+		SetEscrowPaymentAccountAccount(escrow).
+		SetTreasuryMintAccount(aucHouse.AuctionHouseData.TreasuryMint).
+		SetAuthorityAccount(aucHouse.AuctionHouseData.Authority).
+		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
+		SetAuctionHouseFeeAccountAccount(aucHouse.AuctionHouseData.AuctionHouseFeeAccount).
+		SetTokenProgramAccount(solana.TokenProgramID).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		SetRentAccount(solana.SysVarRentPubkey)
+	instruction.GetTransferAuthorityAccount().IsSigner = pay.signsTransfer()
+	return aucHouse.Wm.SendAndConfirmInstructions(
+		wallet.PublicKey(),
+		pay.wrap(wallet.PublicKey(), amount, instruction.Build()),
+		pay.signers(wallet),
+	)
+}
+
+func (aucHouse *AuctionHouseActor) Withdraw(wallet solana.PrivateKey, amount uint64) (*wallet_manager.OperationResult, error) {
+	escrow, escrowBump, err := aucHouse.GetBuyerEscrow(wallet.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	receiptAccount, err := aucHouse.paymentAccount(wallet.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	instruction := auction_house_types.NewWithdrawInstructionBuilder().
+		SetEscrowPaymentBump(escrowBump).
+		SetAmount(amount).
+		SetWalletAccount(wallet.PublicKey()).
+		SetReceiptAccountAccount(receiptAccount).
+		SetEscrowPaymentAccountAccount(escrow).
+		SetTreasuryMintAccount(aucHouse.AuctionHouseData.TreasuryMint).
+		SetAuthorityAccount(aucHouse.AuctionHouseData.Authority).
+		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
+		SetAuctionHouseFeeAccountAccount(aucHouse.AuctionHouseData.AuctionHouseFeeAccount).
+		SetTokenProgramAccount(solana.TokenProgramID).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		SetAtaProgramAccount(solana.SPLAssociatedTokenAccountProgramID).
+		SetRentAccount(solana.SysVarRentPubkey).
+		Build()
+	return aucHouse.Wm.SendAndConfirmInstructions(
+		wallet.PublicKey(),
+		[]solana.Instruction{instruction},
+		[]solana.PrivateKey{wallet},
+	)
+}
+
+// WithdrawAll withdraws the escrow balance which does not back open bids of
+// wallet. It returns a nil result when nothing is left to withdraw. Only bids
+// which printed a receipt can be found, so bids placed without one are not
+// accounted for.
+func (aucHouse *AuctionHouseActor) WithdrawAll(wallet solana.PrivateKey) (uint64, *wallet_manager.OperationResult, error) {
+	balance, err := aucHouse.GetEscrowBalance(wallet.PublicKey())
+	if err != nil || balance == 0 {
+		return 0, nil, err
+	}
+	locked, err := aucHouse.openBidsTotal(wallet.PublicKey())
+	if err != nil {
+		return 0, nil, err
+	}
+	if locked >= balance {
+		return 0, nil, nil
+	}
+	amount := balance - locked
+	result, err := aucHouse.Withdraw(wallet, amount)
+	return amount, result, err
+}
+
+// openBidsTotal sums the prices of bids of buyer whose trade state is still open,
+// which the escrow has to cover when they are accepted.
+func (aucHouse *AuctionHouseActor) openBidsTotal(buyer solana.PublicKey) (uint64, error) {
+	bids, err := aucHouse.GetBuyerBids(buyer)
+	if err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, bid := range bids {
+		open, err := accountExists(aucHouse.Wm, bid.TradeState)
+		if err != nil {
+			return 0, errors.Errorf("failed to get trade state %s. err: %s", bid.TradeState.String(), err.Error())
+		}
+		if open {
+			total += bid.Price
+		}
+	}
+	return total, nil
+}
+
+func (aucHouse *AuctionHouseActor) CloseEscrowAccount(wallet solana.PrivateKey) (*wallet_manager.OperationResult, error) {
+	escrow, escrowBump, err := aucHouse.GetBuyerEscrow(wallet.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	instruction := auction_house_types.NewCloseEscrowAccountInstructionBuilder().
+		SetEscrowPaymentBump(escrowBump).
+		SetWalletAccount(wallet.PublicKey()).
+		SetEscrowPaymentAccountAccount(escrow).
+		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		Build()
+	return aucHouse.Wm.SendAndConfirmInstructions(
+		wallet.PublicKey(),
+		[]solana.Instruction{instruction},
+		[]solana.PrivateKey{wallet},
+	)
+}
+
+// SweepEscrows withdraws leftover escrow balances of wallet from auctionHouses, or
+// from KnownAuctionHouseAccounts when none are given. Funds backing open bids stay
+// in the escrow, see WithdrawAll. A failure on one auction house is reported in its
+// EscrowSweep and does not stop the sweep.
+func SweepEscrows(
+	wm *wallet_manager.WalletManager,
+	wallet solana.PrivateKey,
+	auctionHouses ...solana.PublicKey,
+) []EscrowSweep {
+	if len(auctionHouses) == 0 {
+		auctionHouses = KnownAuctionHouseAccounts()
+	}
+	var sweeps []EscrowSweep
+	for _, auctionHouseAccount := range auctionHouses {
+		sweep := EscrowSweep{AuctionHouse: auctionHouseAccount}
+		actor, err := NewAuctionHouseActor(wm, auctionHouseAccount)
+		if err != nil {
+			sweep.Err = err
+			sweeps = append(sweeps, sweep)
+			continue
+		}
+		sweep.Escrow, _, _ = actor.GetBuyerEscrow(wallet.PublicKey())
+		sweep.Amount, sweep.Result, sweep.Err = actor.WithdrawAll(wallet)
+		if sweep.Amount > 0 || sweep.Err != nil {
+			sweeps = append(sweeps, sweep)
+		}
+	}
+	return sweeps
+}
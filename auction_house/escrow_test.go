@@ -0,0 +1,151 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/auction_house/auction_house_types"
+	"testing"
+)
+
+func TestSweepEscrows(t *testing.T) {
+	fake := newFakeCluster()
+	wm := fake.actor(auction_house_types.AuctionHouse{}).Wm
+	wallet := solana.NewWallet().PrivateKey
+	funded := solana.NewWallet().PublicKey()
+	empty := solana.NewWallet().PublicKey()
+	missing := solana.NewWallet().PublicKey()
+	for _, house := range []solana.PublicKey{funded, empty} {
+		fake.setProgramAccount(house, &auction_house_types.AuctionHouse{
+			Authority:    solana.NewWallet().PublicKey(),
+			TreasuryMint: solana.SolMint,
+		})
+	}
+	fundedActor, err := NewAuctionHouseActor(wm, funded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	escrow, _, _ := fundedActor.GetBuyerEscrow(wallet.PublicKey())
+	fake.SetAccount(escrow, solana.SystemProgramID, 12345, nil)
+
+	sweeps := SweepEscrows(wm, wallet, funded, empty, missing)
+	if len(sweeps) != 2 {
+		t.Fatalf("expected funded and missing auction houses to be reported, got %+v", sweeps)
+	}
+	if !sweeps[0].AuctionHouse.Equals(funded) || sweeps[0].Amount != 12345 || sweeps[0].Err != nil {
+		t.Fatalf("unexpected sweep %+v", sweeps[0])
+	}
+	if !sweeps[1].AuctionHouse.Equals(missing) || sweeps[1].Err == nil {
+		t.Fatalf("expected missing auction house to fail, got %+v", sweeps[1])
+	}
+	withdraw := auction_house_types.NewWithdrawInstructionBuilder()
+	tx := fake.LastSent()
+	withdraw.AccountMetaSlice = tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	if !withdraw.GetEscrowPaymentAccountAccount().PublicKey.Equals(escrow) ||
+		!withdraw.GetReceiptAccountAccount().PublicKey.Equals(wallet.PublicKey()) {
+		t.Fatalf("unexpected withdraw accounts %v", withdraw.AccountMetaSlice)
+	}
+}
+
+func TestAuctionHouseActor_WithdrawAllKeepsOpenBids(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{Authority: solana.NewWallet().PublicKey(), TreasuryMint: solana.SolMint})
+	wallet := solana.NewWallet().PrivateKey
+	escrow, _, _ := actor.GetBuyerEscrow(wallet.PublicKey())
+	fake.SetAccount(escrow, solana.SystemProgramID, 10000, nil)
+
+	canceledAt := int64(1)
+	addBid := func(price uint64, open bool, canceled *int64) {
+		tradeState := solana.NewWallet().PublicKey()
+		if open {
+			fake.SetAccount(tradeState, auction_house_types.ProgramID, 1, []byte{255})
+		}
+		receipt, _, _ := getBidReceipt(tradeState)
+		fake.setProgramAccount(receipt, &auction_house_types.BidReceipt{
+			TradeState:   tradeState,
+			AuctionHouse: actor.AuctionHouseAccount,
+			Buyer:        wallet.PublicKey(),
+			Price:        price,
+			TokenSize:    1,
+			CanceledAt:   canceled,
+		})
+	}
+	addBid(3000, true, nil)
+	addBid(2000, false, nil)
+	addBid(1000, true, &canceledAt)
+
+	amount, result, err := actor.WithdrawAll(wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount != 7000 || result == nil {
+		t.Fatalf("expected the funds of the open bid to stay in escrow, withdrew %d", amount)
+	}
+	tx := fake.LastSent()
+	decoded, err := auction_house_types.DecodeInstruction(tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message), tx.Message.Instructions[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withdraw, ok := decoded.Impl.(*auction_house_types.Withdraw); !ok || *withdraw.Amount != 7000 {
+		t.Fatalf("unexpected withdraw instruction %+v", decoded.Impl)
+	}
+
+	addBid(8000, true, nil)
+	amount, result, err = actor.WithdrawAll(wallet)
+	if err != nil || amount != 0 || result != nil || fake.Calls("sendTransaction") != 1 {
+		t.Fatalf("expected nothing to be withdrawn, got %d. err: %v", amount, err)
+	}
+}
+
+func TestAuctionHouseActor_Deposit(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{Authority: solana.NewWallet().PublicKey(), TreasuryMint: solana.SolMint})
+	wallet := solana.NewWallet().PrivateKey
+	escrow, _, _ := actor.GetBuyerEscrow(wallet.PublicKey())
+
+	if _, err := actor.Deposit(wallet, 5000); err != nil {
+		t.Fatal(err)
+	}
+	tx := fake.LastSent()
+	if len(tx.Message.Instructions) != 1 {
+		t.Fatalf("expected a single deposit instruction, got %d", len(tx.Message.Instructions))
+	}
+	decoded, err := auction_house_types.DecodeInstruction(tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message), tx.Message.Instructions[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deposit, ok := decoded.Impl.(*auction_house_types.Deposit)
+	if !ok || *deposit.Amount != 5000 {
+		t.Fatalf("unexpected deposit instruction %+v", decoded.Impl)
+	}
+	if !deposit.GetWalletAccount().PublicKey.Equals(wallet.PublicKey()) || !deposit.GetWalletAccount().IsSigner ||
+		!deposit.GetPaymentAccountAccount().PublicKey.Equals(wallet.PublicKey()) ||
+		!deposit.GetTransferAuthorityAccount().PublicKey.Equals(solana.SystemProgramID) ||
+		!deposit.GetEscrowPaymentAccountAccount().PublicKey.Equals(escrow) ||
+		!deposit.GetAuctionHouseAccount().PublicKey.Equals(actor.AuctionHouseAccount) {
+		t.Fatalf("unexpected deposit accounts %v", deposit.AccountMetaSlice)
+	}
+}
+
+func TestAuctionHouseActor_CloseEscrowAccount(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{Authority: solana.NewWallet().PublicKey(), TreasuryMint: solana.SolMint})
+	wallet := solana.NewWallet().PrivateKey
+	escrow, _, _ := actor.GetBuyerEscrow(wallet.PublicKey())
+
+	if _, err := actor.CloseEscrowAccount(wallet); err != nil {
+		t.Fatal(err)
+	}
+	tx := fake.LastSent()
+	decoded, err := auction_house_types.DecodeInstruction(tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message), tx.Message.Instructions[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeEscrow, ok := decoded.Impl.(*auction_house_types.CloseEscrowAccount)
+	if !ok {
+		t.Fatalf("unexpected instruction %+v", decoded.Impl)
+	}
+	if !closeEscrow.GetWalletAccount().PublicKey.Equals(wallet.PublicKey()) || !closeEscrow.GetWalletAccount().IsSigner ||
+		!closeEscrow.GetEscrowPaymentAccountAccount().PublicKey.Equals(escrow) ||
+		!closeEscrow.GetAuctionHouseAccount().PublicKey.Equals(actor.AuctionHouseAccount) {
+		t.Fatalf("unexpected close escrow accounts %v", closeEscrow.AccountMetaSlice)
+	}
+}
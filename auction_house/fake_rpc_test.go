@@ -0,0 +1,55 @@
+package auction_house
+
+import (
+	"context"
+	bin "github.com/gagliardetto/binary"
+	token_metadata "github.com/gagliardetto/metaplex-go/clients/token-metadata"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/internal/fakerpc"
+	"solana-go-wm/wallet_manager"
+	"time"
+)
+
+// fakeCluster adds auction house and metadata accounts to the shared fake RPC.
+type fakeCluster struct {
+	*fakerpc.Client
+}
+
+func newFakeCluster() *fakeCluster {
+	return &fakeCluster{Client: fakerpc.New()}
+}
+
+func (fake *fakeCluster) setMetadata(address solana.PublicKey, metadata token_metadata.Metadata) {
+	data, err := bin.MarshalBorsh(&metadata)
+	if err != nil {
+		panic(err)
+	}
+	fake.SetAccount(address, token_metadata.ProgramID, 1, data)
+}
+
+func (fake *fakeCluster) setProgramAccount(address solana.PublicKey, value interface{}) {
+	data, err := bin.MarshalBorsh(value)
+	if err != nil {
+		panic(err)
+	}
+	fake.SetAccount(address, auction_house_types.ProgramID, 1, data)
+}
+
+func (fake *fakeCluster) actor(data auction_house_types.AuctionHouse) *AuctionHouseActor {
+	wm := wallet_manager.NewWalletManagerWithOpts(
+		context.TODO(),
+		rpc.NewWithCustomRPCClient(fake),
+		rpc.CommitmentConfirmed,
+		rpc.ConfirmationStatusConfirmed,
+		time.Second,
+		10*time.Millisecond,
+		false,
+	)
+	return &AuctionHouseActor{
+		Wm:                  wm,
+		AuctionHouseAccount: solana.NewWallet().PublicKey(),
+		AuctionHouseData:    data,
+	}
+}
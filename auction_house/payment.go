@@ -0,0 +1,63 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+)
+
+// payment describes how a wallet pays into its escrow. Native SOL houses take
+// lamports from the wallet itself. SPL-token houses take tokens from the wallet's
+// treasury mint ATA through a one-off delegate, which is approved right before
+// and revoked right after the paying instruction.
+type payment struct {
+	account   solana.PublicKey
+	authority solana.PublicKey
+	delegate  solana.PrivateKey
+}
+
+func (aucHouse *AuctionHouseActor) newPayment(wallet solana.PublicKey) (payment, error) {
+	if aucHouse.isNativeTreasury() {
+		return payment{account: wallet, authority: solana.SystemProgramID}, nil
+	}
+	account, err := aucHouse.paymentAccount(wallet)
+	if err != nil {
+		return payment{}, err
+	}
+	delegate := solana.NewWallet().PrivateKey
+	return payment{account: account, authority: delegate.PublicKey(), delegate: delegate}, nil
+}
+
+// paymentAccount is where wallet receives auction house payments: the wallet for
+// native SOL houses and its treasury mint ATA otherwise.
+func (aucHouse *AuctionHouseActor) paymentAccount(wallet solana.PublicKey) (solana.PublicKey, error) {
+	if aucHouse.isNativeTreasury() {
+		return wallet, nil
+	}
+	account, _, err := solana.FindAssociatedTokenAddress(wallet, aucHouse.AuctionHouseData.TreasuryMint)
+	return account, err
+}
+
+// signsTransfer reports whether the transfer authority of the paying instruction
+// signs. The delegate has to although the program IDL does not mark it as a signer.
+func (p payment) signsTransfer() bool {
+	return p.delegate != nil
+}
+
+// wrap approves the delegate for amount before instructions and revokes it after
+// them. Receipt instructions go into the same call so that they still directly
+// follow the instruction they print the receipt for.
+func (p payment) wrap(owner solana.PublicKey, amount uint64, instructions ...solana.Instruction) []solana.Instruction {
+	if p.delegate == nil {
+		return instructions
+	}
+	wrapped := []solana.Instruction{token.NewApproveInstruction(amount, p.account, p.authority, owner, nil).Build()}
+	wrapped = append(wrapped, instructions...)
+	return append(wrapped, token.NewRevokeInstruction(p.account, owner, nil).Build())
+}
+
+func (p payment) signers(wallet solana.PrivateKey) []solana.PrivateKey {
+	if p.delegate == nil {
+		return []solana.PrivateKey{wallet}
+	}
+	return []solana.PrivateKey{wallet, p.delegate}
+}
@@ -0,0 +1,234 @@
+package auction_house
+
+import (
+	token_metadata "github.com/gagliardetto/metaplex-go/clients/token-metadata"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"solana-go-wm/auction_house/auction_house_types"
+	"testing"
+)
+
+func TestAuctionHouseActor_BuySplTreasury(t *testing.T) {
+	fake := newFakeCluster()
+	usdc := solana.NewWallet().PublicKey()
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:    solana.NewWallet().PublicKey(),
+		TreasuryMint: usdc,
+	})
+	buyer := solana.NewWallet().PrivateKey
+	seller := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	mintAta, _, _ := solana.FindAssociatedTokenAddress(seller, mint)
+	metadata, _ := getMetadata(mint)
+	fake.setMetadata(metadata, token_metadata.Metadata{Mint: mint})
+
+	if _, err := actor.Buy(buyer, AuctionHouseBuyData{
+		Owner:       seller,
+		MintAddress: mint,
+		MintAta:     mintAta,
+		Price:       5000,
+		TokenSize:   1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tx := fake.LastSent()
+	if len(tx.Message.Instructions) != 4 {
+		t.Fatalf("expected approve, buy, revoke and execute sale, got %d instructions", len(tx.Message.Instructions))
+	}
+	programs := []solana.PublicKey{solana.TokenProgramID, auction_house_types.ProgramID, solana.TokenProgramID, auction_house_types.ProgramID}
+	for idx, program := range programs {
+		programID, _ := tx.Message.ResolveProgramIDIndex(tx.Message.Instructions[idx].ProgramIDIndex)
+		if !programID.Equals(program) {
+			t.Fatalf("expected instruction %d to call %s, got %s", idx, program, programID)
+		}
+	}
+	buyerAta, _, _ := solana.FindAssociatedTokenAddress(buyer.PublicKey(), usdc)
+	buyAccounts := tx.Message.Instructions[1].ResolveInstructionAccounts(&tx.Message)
+	if !buyAccounts[1].PublicKey.Equals(buyerAta) {
+		t.Fatalf("expected payment account %s, got %s", buyerAta, buyAccounts[1].PublicKey)
+	}
+	if !buyAccounts[2].IsSigner || buyAccounts[2].PublicKey.Equals(solana.SystemProgramID) {
+		t.Fatalf("expected a signing delegate transfer authority, got %v", buyAccounts[2])
+	}
+	if len(tx.Signatures) != 2 {
+		t.Fatalf("expected buyer and delegate signatures, got %d", len(tx.Signatures))
+	}
+	sellerAta, _, _ := solana.FindAssociatedTokenAddress(seller, usdc)
+	saleAccounts := tx.Message.Instructions[3].ResolveInstructionAccounts(&tx.Message)
+	if !saleAccounts[7].PublicKey.Equals(sellerAta) {
+		t.Fatalf("expected seller payment account %s, got %s", sellerAta, saleAccounts[7].PublicKey)
+	}
+}
+
+func newSplTreasuryActor(fake *fakeCluster) (*AuctionHouseActor, solana.PublicKey) {
+	usdc := solana.NewWallet().PublicKey()
+	return fake.actor(auction_house_types.AuctionHouse{
+		Authority:    solana.NewWallet().PublicKey(),
+		TreasuryMint: usdc,
+	}), usdc
+}
+
+// decodeInstructions decodes the auction house and token program instructions of
+// tx in order.
+func decodeInstructions(t *testing.T, tx *solana.Transaction) []interface{} {
+	var decoded []interface{}
+	for _, instruction := range tx.Message.Instructions {
+		programID, _ := tx.Message.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+		accounts := instruction.ResolveInstructionAccounts(&tx.Message)
+		switch {
+		case programID.Equals(auction_house_types.ProgramID):
+			inst, err := auction_house_types.DecodeInstruction(accounts, instruction.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded = append(decoded, inst.Impl)
+		case programID.Equals(solana.TokenProgramID):
+			inst, err := token.DecodeInstruction(accounts, instruction.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded = append(decoded, inst.Impl)
+		default:
+			t.Fatalf("unexpected program %s", programID)
+		}
+	}
+	return decoded
+}
+
+func TestAuctionHouseActor_PlaceBidSplTreasury(t *testing.T) {
+	fake := newFakeCluster()
+	actor, usdc := newSplTreasuryActor(fake)
+	buyer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	tokenAccount := solana.NewWallet().PublicKey()
+	buyerAta, _, _ := solana.FindAssociatedTokenAddress(buyer.PublicKey(), usdc)
+
+	tradeState, _, err := actor.PlaceBid(buyer, AuctionHouseBidData{
+		MintAddress:  mint,
+		TokenAccount: tokenAccount,
+		Price:        5000,
+		TokenSize:    1,
+		PrintReceipt: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := decodeInstructions(t, fake.LastSent())
+	if len(decoded) != 4 {
+		t.Fatalf("expected approve, buy, receipt and revoke, got %d instructions", len(decoded))
+	}
+	approve, ok := decoded[0].(*token.Approve)
+	if !ok || *approve.Amount != 5000 {
+		t.Fatalf("expected approve of the price first, got %+v", decoded[0])
+	}
+	buy, ok := decoded[1].(*auction_house_types.Buy)
+	if !ok || !buy.GetBuyerTradeStateAccount().PublicKey.Equals(tradeState) {
+		t.Fatalf("expected buy second, got %+v", decoded[1])
+	}
+	if !buy.GetPaymentAccountAccount().PublicKey.Equals(buyerAta) ||
+		!buy.GetTransferAuthorityAccount().PublicKey.Equals(approve.GetDelegateAccount().PublicKey) ||
+		!buy.GetTransferAuthorityAccount().IsSigner {
+		t.Fatalf("expected the approved delegate to sign the buy, got %v", buy.AccountMetaSlice)
+	}
+	if _, ok = decoded[2].(*auction_house_types.PrintBidReceipt); !ok {
+		t.Fatalf("expected the receipt to directly follow the buy, got %+v", decoded[2])
+	}
+	if _, ok = decoded[3].(*token.Revoke); !ok {
+		t.Fatalf("expected revoke last, got %+v", decoded[3])
+	}
+}
+
+func TestAuctionHouseActor_PlacePublicBidSplTreasury(t *testing.T) {
+	fake := newFakeCluster()
+	actor, usdc := newSplTreasuryActor(fake)
+	buyer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	buyerAta, _, _ := solana.FindAssociatedTokenAddress(buyer.PublicKey(), usdc)
+
+	tradeState, _, err := actor.PlacePublicBid(buyer, AuctionHouseBidData{
+		MintAddress:  mint,
+		TokenAccount: solana.NewWallet().PublicKey(),
+		Price:        5000,
+		TokenSize:    1,
+		PrintReceipt: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := decodeInstructions(t, fake.LastSent())
+	if len(decoded) != 4 {
+		t.Fatalf("expected approve, public buy, receipt and revoke, got %d instructions", len(decoded))
+	}
+	approve, ok := decoded[0].(*token.Approve)
+	if !ok || *approve.Amount != 5000 {
+		t.Fatalf("expected approve of the price first, got %+v", decoded[0])
+	}
+	buy, ok := decoded[1].(*auction_house_types.PublicBuy)
+	if !ok || !buy.GetBuyerTradeStateAccount().PublicKey.Equals(tradeState) {
+		t.Fatalf("expected public buy second, got %+v", decoded[1])
+	}
+	if !buy.GetPaymentAccountAccount().PublicKey.Equals(buyerAta) ||
+		!buy.GetTransferAuthorityAccount().PublicKey.Equals(approve.GetDelegateAccount().PublicKey) ||
+		!buy.GetTransferAuthorityAccount().IsSigner {
+		t.Fatalf("expected the approved delegate to sign the public buy, got %v", buy.AccountMetaSlice)
+	}
+	if _, ok = decoded[2].(*auction_house_types.PrintBidReceipt); !ok {
+		t.Fatalf("expected the receipt to directly follow the public buy, got %+v", decoded[2])
+	}
+	if _, ok = decoded[3].(*token.Revoke); !ok {
+		t.Fatalf("expected revoke last, got %+v", decoded[3])
+	}
+}
+
+func TestAuctionHouseActor_DepositSplTreasury(t *testing.T) {
+	fake := newFakeCluster()
+	actor, usdc := newSplTreasuryActor(fake)
+	wallet := solana.NewWallet().PrivateKey
+	walletAta, _, _ := solana.FindAssociatedTokenAddress(wallet.PublicKey(), usdc)
+
+	if _, err := actor.Deposit(wallet, 5000); err != nil {
+		t.Fatal(err)
+	}
+	decoded := decodeInstructions(t, fake.LastSent())
+	if len(decoded) != 3 {
+		t.Fatalf("expected approve, deposit and revoke, got %d instructions", len(decoded))
+	}
+	approve, ok := decoded[0].(*token.Approve)
+	if !ok || *approve.Amount != 5000 || !approve.GetSourceAccount().PublicKey.Equals(walletAta) {
+		t.Fatalf("expected approve of the wallet ATA first, got %+v", decoded[0])
+	}
+	deposit, ok := decoded[1].(*auction_house_types.Deposit)
+	if !ok || *deposit.Amount != 5000 {
+		t.Fatalf("expected deposit second, got %+v", decoded[1])
+	}
+	if !deposit.GetPaymentAccountAccount().PublicKey.Equals(walletAta) ||
+		!deposit.GetTransferAuthorityAccount().PublicKey.Equals(approve.GetDelegateAccount().PublicKey) ||
+		!deposit.GetTransferAuthorityAccount().IsSigner {
+		t.Fatalf("expected the approved delegate to sign the deposit, got %v", deposit.AccountMetaSlice)
+	}
+	if _, ok = decoded[2].(*token.Revoke); !ok {
+		t.Fatalf("expected revoke last, got %+v", decoded[2])
+	}
+}
+
+func TestAuctionHouseActor_WithdrawSplTreasury(t *testing.T) {
+	fake := newFakeCluster()
+	actor, usdc := newSplTreasuryActor(fake)
+	wallet := solana.NewWallet().PrivateKey
+	walletAta, _, _ := solana.FindAssociatedTokenAddress(wallet.PublicKey(), usdc)
+	escrow, _, _ := actor.GetBuyerEscrow(wallet.PublicKey())
+
+	if _, err := actor.Withdraw(wallet, 5000); err != nil {
+		t.Fatal(err)
+	}
+	decoded := decodeInstructions(t, fake.LastSent())
+	withdraw, ok := decoded[0].(*auction_house_types.Withdraw)
+	if len(decoded) != 1 || !ok || *withdraw.Amount != 5000 {
+		t.Fatalf("expected a single withdraw, got %+v", decoded)
+	}
+	if !withdraw.GetReceiptAccountAccount().PublicKey.Equals(walletAta) ||
+		!withdraw.GetEscrowPaymentAccountAccount().PublicKey.Equals(escrow) ||
+		!withdraw.GetTreasuryMintAccount().PublicKey.Equals(usdc) {
+		t.Fatalf("expected withdraw into the wallet ATA, got %v", withdraw.AccountMetaSlice)
+	}
+}
@@ -0,0 +1,95 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+)
+
+const (
+	PolicyActionBuy       wallet_manager.PolicyActionKind = "auction_house_buy"
+	PolicyActionDeposit   wallet_manager.PolicyActionKind = "auction_house_deposit"
+	PolicyRuleMaxBuyPrice wallet_manager.PolicyRule       = "max_auction_house_buy_price"
+)
+
+func init() {
+	wallet_manager.RegisterPolicyDecoder(auction_house_types.ProgramID, decodePolicyActions)
+}
+
+// BuyPolicy limits auction house bids. Actors register it on the policy engine of
+// their manager when the SpendingPolicy sets MaxAuctionHouseBuyPrice, other engines
+// can use engine.AddCheck(policy.Check). A zero MaxBuyPrice means unlimited.
+type BuyPolicy struct {
+	MaxBuyPrice uint64 `json:"maxBuyPrice"`
+}
+
+func (policy BuyPolicy) Check(action wallet_manager.PolicyAction) error {
+	if action.Kind != PolicyActionBuy || policy.MaxBuyPrice == 0 || action.Amount <= policy.MaxBuyPrice {
+		return nil
+	}
+	return &wallet_manager.PolicyViolationError{
+		Rule:      PolicyRuleMaxBuyPrice,
+		Action:    action,
+		Limit:     policy.MaxBuyPrice,
+		Requested: action.Amount,
+	}
+}
+
+// setBuyPolicy registers the max buy price of the spending policy of wm.
+func setBuyPolicy(wm *wallet_manager.WalletManager) {
+	if wm.Policy == nil || wm.Policy.Policy.MaxAuctionHouseBuyPrice == 0 {
+		return
+	}
+	policy := BuyPolicy{MaxBuyPrice: wm.Policy.Policy.MaxAuctionHouseBuyPrice}
+	wm.Policy.SetCheck(string(PolicyRuleMaxBuyPrice), policy.Check)
+}
+
+// decodePolicyActions reports bids and escrow deposits as spending of the bidding
+// wallet in the treasury mint, and fee and treasury withdrawals as transfers of the
+// authority. Withdrawals from escrow return funds to the buyer, and sales pay out of
+// escrow funded by bids already counted, so neither is reported.
+func decodePolicyActions(accounts []*solana.AccountMeta, data []byte) ([]wallet_manager.PolicyAction, error) {
+	decoded, err := auction_house_types.DecodeInstruction(accounts, data)
+	if err != nil {
+		return nil, err
+	}
+	switch impl := decoded.Impl.(type) {
+	case *auction_house_types.Buy:
+		return []wallet_manager.PolicyAction{{
+			Kind:   PolicyActionBuy,
+			Wallet: impl.GetWalletAccount().PublicKey,
+			Mint:   impl.GetTreasuryMintAccount().PublicKey,
+			Amount: *impl.BuyerPrice,
+		}}, nil
+	case *auction_house_types.PublicBuy:
+		return []wallet_manager.PolicyAction{{
+			Kind:   PolicyActionBuy,
+			Wallet: impl.GetWalletAccount().PublicKey,
+			Mint:   impl.GetTreasuryMintAccount().PublicKey,
+			Amount: *impl.BuyerPrice,
+		}}, nil
+	case *auction_house_types.Deposit:
+		return []wallet_manager.PolicyAction{{
+			Kind:   PolicyActionDeposit,
+			Wallet: impl.GetWalletAccount().PublicKey,
+			Mint:   impl.GetTreasuryMintAccount().PublicKey,
+			Amount: *impl.Amount,
+		}}, nil
+	case *auction_house_types.WithdrawFromFee:
+		return []wallet_manager.PolicyAction{{
+			Kind:      wallet_manager.PolicyActionTransfer,
+			Wallet:    impl.GetAuthorityAccount().PublicKey,
+			Recipient: impl.GetFeeWithdrawalDestinationAccount().PublicKey,
+			Amount:    *impl.Amount,
+		}}, nil
+	case *auction_house_types.WithdrawFromTreasury:
+		return []wallet_manager.PolicyAction{{
+			Kind:      wallet_manager.PolicyActionTransfer,
+			Wallet:    impl.GetAuthorityAccount().PublicKey,
+			Recipient: impl.GetTreasuryWithdrawalDestinationAccount().PublicKey,
+			Mint:      impl.GetTreasuryMintAccount().PublicKey,
+			Amount:    *impl.Amount,
+		}}, nil
+	}
+	return nil, nil
+}
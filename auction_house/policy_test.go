@@ -0,0 +1,101 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+	"testing"
+)
+
+func TestBuyPolicy_MaxBuyPrice(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:    solana.NewWallet().PublicKey(),
+		TreasuryMint: solana.SolMint,
+	})
+	actor.Wm.Policy = wallet_manager.NewPolicyEngine(wallet_manager.SpendingPolicy{})
+	actor.Wm.Policy.AddCheck(BuyPolicy{MaxBuyPrice: 500}.Check)
+	buyer := solana.NewWallet().PrivateKey
+	bid := AuctionHouseBidData{
+		MintAddress:  solana.NewWallet().PublicKey(),
+		TokenAccount: solana.NewWallet().PublicKey(),
+		Price:        700,
+		TokenSize:    1,
+	}
+
+	_, _, err := actor.PlaceBid(buyer, bid)
+	violation, ok := err.(*wallet_manager.PolicyViolationError)
+	if !ok || violation.Rule != PolicyRuleMaxBuyPrice || violation.Action.Amount != 700 {
+		t.Fatalf("expected max buy price violation, got %v", err)
+	}
+	if fake.Calls("sendTransaction") != 0 {
+		t.Fatal("rejected bid must not be sent")
+	}
+	bid.Price = 500
+	if _, _, err = actor.PlaceBid(buyer, bid); err != nil {
+		t.Fatal(err)
+	}
+	if spent := actor.Wm.Policy.SpentLamports(buyer.PublicKey()); spent != 500 {
+		t.Fatalf("expected the bid to count as spending, spent %d", spent)
+	}
+}
+
+func TestNewAuctionHouseActor_BuyPolicyFromSpendingPolicy(t *testing.T) {
+	fake := newFakeCluster()
+	wm := fake.actor(auction_house_types.AuctionHouse{}).Wm
+	wm.Policy = wallet_manager.NewPolicyEngine(wallet_manager.SpendingPolicy{MaxAuctionHouseBuyPrice: 500})
+	house := solana.NewWallet().PublicKey()
+	fake.setProgramAccount(house, &auction_house_types.AuctionHouse{
+		Authority:    solana.NewWallet().PublicKey(),
+		TreasuryMint: solana.SolMint,
+	})
+	var actor *AuctionHouseActor
+	for i := 0; i < 2; i++ {
+		var err error
+		if actor, err = NewAuctionHouseActor(wm, house); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(wm.Policy.Checks) != 1 {
+		t.Fatalf("expected the buy policy to be registered once, got %d checks", len(wm.Policy.Checks))
+	}
+
+	_, _, err := actor.PlaceBid(solana.NewWallet().PrivateKey, AuctionHouseBidData{
+		MintAddress:  solana.NewWallet().PublicKey(),
+		TokenAccount: solana.NewWallet().PublicKey(),
+		Price:        700,
+		TokenSize:    1,
+	})
+	if violation, ok := err.(*wallet_manager.PolicyViolationError); !ok || violation.Rule != PolicyRuleMaxBuyPrice {
+		t.Fatalf("expected max buy price violation, got %v", err)
+	}
+}
+
+func TestAuctionHouseActor_PolicyCountsDepositsAndWithdrawals(t *testing.T) {
+	fake := newFakeCluster()
+	authority := solana.NewWallet().PrivateKey
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:                     authority.PublicKey(),
+		TreasuryMint:                  solana.SolMint,
+		FeeWithdrawalDestination:      solana.NewWallet().PublicKey(),
+		TreasuryWithdrawalDestination: solana.NewWallet().PublicKey(),
+	})
+	actor.Wm.Policy = wallet_manager.NewPolicyEngine(wallet_manager.SpendingPolicy{})
+	wallet := solana.NewWallet().PrivateKey
+
+	if _, err := actor.Deposit(wallet, 5000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := actor.WithdrawFromFee(authority, 300); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := actor.WithdrawFromTreasury(authority, 200); err != nil {
+		t.Fatal(err)
+	}
+	if spent := actor.Wm.Policy.SpentLamports(wallet.PublicKey()); spent != 5000 {
+		t.Fatalf("expected the deposit to count as spending, spent %d", spent)
+	}
+	if spent := actor.Wm.Policy.SpentLamports(authority.PublicKey()); spent != 500 {
+		t.Fatalf("expected fee and treasury withdrawals to count as spending, spent %d", spent)
+	}
+}
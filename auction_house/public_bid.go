@@ -0,0 +1,198 @@
+package auction_house
+
+import (
+	"encoding/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+)
+
+// PlacePublicBid places a bid for tokenSize tokens of data.MintAddress which any
+// holder can accept. data.TokenAccount only has to hold the mint; when it is not
+// set the largest holder's account is used.
+func (aucHouse *AuctionHouseActor) PlacePublicBid(
+	buyer solana.PrivateKey,
+	data AuctionHouseBidData,
+) (solana.PublicKey, *wallet_manager.OperationResult, error) {
+	if data.Price == 0 || data.TokenSize == 0 {
+		return solana.PublicKey{}, nil, errors.New("bid price and token size must be positive")
+	}
+	tokenAccount := data.TokenAccount
+	if tokenAccount.IsZero() {
+		var err error
+		tokenAccount, _, err = aucHouse.findTokenHolder(data.MintAddress)
+		if err != nil {
+			return solana.PublicKey{}, nil, err
+		}
+	}
+	pay, err := aucHouse.newPayment(buyer.PublicKey())
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	buyInstruction, err := aucHouse.makePublicBuyInstruction(buyer.PublicKey(), pay, tokenAccount, data.MintAddress, data.Price, data.TokenSize)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	tradeState := buyInstruction.GetBuyerTradeStateAccount().PublicKey
+	instructions := []solana.Instruction{buyInstruction.Build()}
+	if data.PrintReceipt {
+		receiptInstruction, err := makePrintBidReceiptInstruction(buyer.PublicKey(), tradeState)
+		if err != nil {
+			return solana.PublicKey{}, nil, err
+		}
+		instructions = append(instructions, receiptInstruction)
+	}
+	instructions = pay.wrap(buyer.PublicKey(), data.Price, instructions...)
+	result, err := aucHouse.send(
+		buyer.PublicKey(),
+		wallet_manager.AppendMemo(instructions, data.Memo, data.SignMemo, buyer.PublicKey()),
+		pay.signers(buyer),
+	)
+	return tradeState, result, err
+}
+
+func (aucHouse *AuctionHouseActor) CancelPublicBid(
+	buyer solana.PrivateKey,
+	mint solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+) (*wallet_manager.OperationResult, error) {
+	tradeState, _, err := aucHouse.getPublicTradeState(buyer.PublicKey(), mint, price, tokenSize)
+	if err != nil {
+		return nil, err
+	}
+	tokenAccount, err := aucHouse.findPublicBidTokenAccount(tradeState)
+	if err != nil {
+		return nil, err
+	}
+	return aucHouse.cancelBid(buyer, tokenAccount, mint, tradeState, price, tokenSize)
+}
+
+// findPublicBidTokenAccount returns the token account a public bid was placed with.
+// Public bid receipts do not record it, so it is read from the public buy
+// instruction which created tradeState.
+func (aucHouse *AuctionHouseActor) findPublicBidTokenAccount(tradeState solana.PublicKey) (solana.PublicKey, error) {
+	var tokenAccount solana.PublicKey
+	err := aucHouse.searchInstructions(tradeState, func(instruction *auction_house_types.Instruction) (bool, error) {
+		buy, ok := instruction.Impl.(*auction_house_types.PublicBuy)
+		if !ok || !buy.GetBuyerTradeStateAccount().PublicKey.Equals(tradeState) {
+			return false, nil
+		}
+		tokenAccount = buy.GetTokenAccountAccount().PublicKey
+		return true, nil
+	})
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	if tokenAccount.IsZero() {
+		return solana.PublicKey{}, errors.Errorf("public buy of trade state %s not found", tradeState.String())
+	}
+	return tokenAccount, nil
+}
+
+// AcceptPublicBid lists the seller's tokens at the bid price and executes the sale
+// against the public bid of data.Buyer in one transaction.
+func (aucHouse *AuctionHouseActor) AcceptPublicBid(
+	seller solana.PrivateKey,
+	data AuctionHouseAcceptBidData,
+) (*wallet_manager.OperationResult, error) {
+	data.Public = true
+	return aucHouse.AcceptBid(seller, data)
+}
+
+func (aucHouse *AuctionHouseActor) makePublicBuyInstruction(
+	buyer solana.PublicKey,
+	pay payment,
+	tokenAccount,
+	mint solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+) (*auction_house_types.PublicBuy, error) {
+	buyerEscrowAccount, buyerEscrowBump, err := aucHouse.GetBuyerEscrow(buyer)
+	if err != nil {
+		return nil, err
+	}
+	tradeState, tradeStateBump, err := aucHouse.getPublicTradeState(buyer, mint, price, tokenSize)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := getMetadata(mint)
+	if err != nil {
+		return nil, err
+	}
+	buyInstruction := auction_house_types.NewPublicBuyInstructionBuilder().
+		SetTradeStateBump(tradeStateBump).
+		SetEscrowPaymentBump(buyerEscrowBump).
+		SetBuyerPrice(price).
+		SetTokenSize(tokenSize).
+		SetWalletAccount(buyer).
+		SetPaymentAccountAccount(pay.account).
+		SetTransferAuthorityAccount(pay.authority).
+		SetTreasuryMintAccount(aucHouse.AuctionHouseData.TreasuryMint).
+		SetTokenAccountAccount(tokenAccount).
+		SetMetadataAccount(metadata).
+		SetEscrowPaymentAccountAccount(buyerEscrowAccount).
+		SetAuthorityAccount(aucHouse.AuctionHouseData.Authority).
+		SetAuctionHouseAccount(aucHouse.AuctionHouseAccount).
+		SetAuctionHouseFeeAccountAccount(aucHouse.AuctionHouseData.AuctionHouseFeeAccount).
+		SetBuyerTradeStateAccount(tradeState).
+		SetTokenProgramAccount(solana.TokenProgramID).
+		SetSystemProgramAccount(solana.SystemProgramID).
+		SetRentAccount(solana.SysVarRentPubkey)
+	buyInstruction.GetTransferAuthorityAccount().IsSigner = pay.signsTransfer()
+	buyInstruction.GetAuthorityAccount().IsSigner = aucHouse.authoritySigns()
+	return buyInstruction, nil
+}
+
+// getPublicTradeState derives the trade state of a public bid, which unlike
+// getTradeState leaves the token account out of the seeds.
+func (aucHouse *AuctionHouseActor) getPublicTradeState(
+	wallet,
+	tokenMint solana.PublicKey,
+	price uint64,
+	tokenSize uint64,
+) (solana.PublicKey, uint8, error) {
+	buyPriceBytes := make([]byte, 8)
+	tokenSizeBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(tokenSizeBytes, tokenSize)
+	binary.LittleEndian.PutUint64(buyPriceBytes, price)
+	return solana.FindProgramAddress(
+		[][]byte{
+			[]byte(auctionHouse),
+			wallet.Bytes(),
+			aucHouse.AuctionHouseAccount.Bytes(),
+			aucHouse.AuctionHouseData.TreasuryMint.Bytes(),
+			tokenMint.Bytes(),
+			buyPriceBytes,
+			tokenSizeBytes,
+		},
+		auction_house_types.ProgramID,
+	)
+}
+
+// findTokenHolder returns the largest non-empty token account of mint and its owner.
+func (aucHouse *AuctionHouseActor) findTokenHolder(mint solana.PublicKey) (solana.PublicKey, solana.PublicKey, error) {
+	largest, err := aucHouse.Wm.Client.GetTokenLargestAccounts(aucHouse.Wm.Context, mint, aucHouse.Wm.Commitment)
+	if err != nil {
+		return solana.PublicKey{}, solana.PublicKey{}, errors.Errorf("failed to get holders of %s. err: %s", mint.String(), err.Error())
+	}
+	for _, holder := range largest.Value {
+		if holder.Amount == "0" {
+			continue
+		}
+		account, err := aucHouse.Wm.Client.GetAccountInfoWithOpts(aucHouse.Wm.Context, holder.Address, &rpc.GetAccountInfoOpts{
+			Commitment: aucHouse.Wm.Commitment,
+		})
+		if err != nil {
+			return solana.PublicKey{}, solana.PublicKey{}, errors.Errorf("failed to get token account %s. err: %s", holder.Address.String(), err.Error())
+		}
+		data := account.Value.Data.GetBinary()
+		if len(data) < 64 {
+			continue
+		}
+		return holder.Address, solana.PublicKeyFromBytes(data[32:64]), nil
+	}
+	return solana.PublicKey{}, solana.PublicKey{}, errors.Errorf("no holder found for %s", mint.String())
+}
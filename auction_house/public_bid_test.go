@@ -0,0 +1,114 @@
+package auction_house
+
+import (
+	token_metadata "github.com/gagliardetto/metaplex-go/clients/token-metadata"
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"solana-go-wm/auction_house/auction_house_types"
+	"testing"
+)
+
+func TestAuctionHouseActor_PublicBid(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:    solana.NewWallet().PublicKey(),
+		TreasuryMint: solana.SolMint,
+	})
+	buyer := solana.NewWallet().PrivateKey
+	seller := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	sellerAta, _, _ := solana.FindAssociatedTokenAddress(seller.PublicKey(), mint)
+	fake.SetTokenAccount(sellerAta, mint, seller.PublicKey(), 1)
+	metadata, _ := getMetadata(mint)
+	fake.setMetadata(metadata, token_metadata.Metadata{Mint: mint})
+
+	tradeState, _, err := actor.PlacePublicBid(buyer, AuctionHouseBidData{MintAddress: mint, Price: 700, TokenSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, _, _ := actor.getTradeState(buyer.PublicKey(), sellerAta, mint, 700, 1)
+	if tradeState.Equals(private) {
+		t.Fatal("public trade state must not depend on the token account")
+	}
+	buy := auction_house_types.NewPublicBuyInstructionBuilder()
+	buy.AccountMetaSlice = fake.LastSent().Message.Instructions[0].ResolveInstructionAccounts(&fake.LastSent().Message)
+	if !buy.GetTokenAccountAccount().PublicKey.Equals(sellerAta) || !buy.GetBuyerTradeStateAccount().PublicKey.Equals(tradeState) {
+		t.Fatalf("unexpected public buy accounts %v", buy.AccountMetaSlice)
+	}
+
+	acceptData := AuctionHouseAcceptBidData{Buyer: buyer.PublicKey(), MintAddress: mint, Price: 700, TokenSize: 1}
+	if _, err = actor.AcceptPublicBid(seller, acceptData); !errors.Is(err, ErrTradeStateClosed) {
+		t.Fatalf("expected closed trade state error, got %v", err)
+	}
+	fake.SetAccount(tradeState, auction_house_types.ProgramID, 1, []byte{255})
+	if _, err = actor.AcceptPublicBid(seller, acceptData); err != nil {
+		t.Fatal(err)
+	}
+	tx := fake.LastSent()
+	if len(tx.Message.Instructions) != 2 {
+		t.Fatalf("expected sell and execute sale instructions, got %d", len(tx.Message.Instructions))
+	}
+	sale := auction_house_types.NewExecuteSaleInstructionBuilder()
+	sale.AccountMetaSlice = tx.Message.Instructions[1].ResolveInstructionAccounts(&tx.Message)
+	if !sale.GetBuyerTradeStateAccount().PublicKey.Equals(tradeState) || !sale.GetTokenAccountAccount().PublicKey.Equals(sellerAta) {
+		t.Fatalf("unexpected execute sale accounts %v", sale.AccountMetaSlice)
+	}
+}
+
+func TestAuctionHouseActor_CancelPublicBid(t *testing.T) {
+	fake := newFakeCluster()
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:    solana.NewWallet().PublicKey(),
+		TreasuryMint: solana.SolMint,
+	})
+	buyer := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+	bidAccount := solana.NewWallet().PublicKey()
+	fake.SetTokenAccount(bidAccount, mint, solana.NewWallet().PublicKey(), 1)
+	metadata, _ := getMetadata(mint)
+	fake.setMetadata(metadata, token_metadata.Metadata{Mint: mint})
+
+	tradeState, _, err := actor.PlacePublicBid(buyer, AuctionHouseBidData{MintAddress: mint, TokenAccount: bidAccount, Price: 700, TokenSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.AddHistory(fake.LastSent())
+	fake.SetAccount(tradeState, auction_house_types.ProgramID, 1, []byte{255})
+	// the largest holder changed since the bid was placed
+	fake.SetTokenAccount(solana.NewWallet().PublicKey(), mint, solana.NewWallet().PublicKey(), 5)
+
+	cancelAccount := func() solana.PublicKey {
+		cancel := auction_house_types.NewCancelInstructionBuilder()
+		cancel.AccountMetaSlice = fake.LastSent().Message.Instructions[0].ResolveInstructionAccounts(&fake.LastSent().Message)
+		if !cancel.GetTradeStateAccount().PublicKey.Equals(tradeState) {
+			t.Fatalf("unexpected cancel accounts %v", cancel.AccountMetaSlice)
+		}
+		return cancel.GetTokenAccountAccount().PublicKey
+	}
+	if _, err = actor.CancelPublicBid(buyer, mint, 700, 1); err != nil {
+		t.Fatal(err)
+	}
+	if account := cancelAccount(); !account.Equals(bidAccount) {
+		t.Fatalf("expected the bid token account %s, got %s", bidAccount, account)
+	}
+
+	receipt, _, _ := getBidReceipt(tradeState)
+	fake.setProgramAccount(receipt, &auction_house_types.BidReceipt{
+		TradeState:   tradeState,
+		AuctionHouse: actor.AuctionHouseAccount,
+		Buyer:        buyer.PublicKey(),
+		Metadata:     metadata,
+		Price:        700,
+		TokenSize:    1,
+	})
+	if _, err = actor.CancelBidByReceipt(buyer, receipt); err != nil {
+		t.Fatal(err)
+	}
+	if account := cancelAccount(); !account.Equals(bidAccount) {
+		t.Fatalf("expected the bid token account %s, got %s", bidAccount, account)
+	}
+
+	if _, err = actor.CancelPublicBid(buyer, mint, 800, 1); err == nil {
+		t.Fatal("expected a bid without public buy to fail")
+	}
+}
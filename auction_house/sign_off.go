@@ -0,0 +1,105 @@
+package auction_house
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"solana-go-wm/wallet_manager"
+)
+
+// SignOffRequiredError carries a trade signed by the trader which still needs the
+// signature of the auction house authority. Hand Transaction to the authority's
+// signing service and pass the result to SubmitSignedOff.
+type SignOffRequiredError struct {
+	Authority   solana.PublicKey
+	Transaction *wallet_manager.PartialTransaction
+}
+
+func (e *SignOffRequiredError) Error() string {
+	return fmt.Sprintf("transaction requires sign-off by auction house authority %s", e.Authority.String())
+}
+
+func IsSignOffRequired(err error) bool {
+	_, ok := errors.Cause(err).(*SignOffRequiredError)
+	return ok
+}
+
+// SubmitSignedOff sends signed once the authority has co-signed it. signed must
+// carry exactly the message of prepared, the transaction of the SignOffRequiredError.
+func (aucHouse *AuctionHouseActor) SubmitSignedOff(
+	prepared *wallet_manager.PartialTransaction,
+	signed *wallet_manager.PartialTransaction,
+) (*wallet_manager.OperationResult, error) {
+	preparedMessage, err := prepared.Transaction.Message.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	signedMessage, err := signed.Transaction.Message.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(preparedMessage, signedMessage) {
+		return nil, errors.New("signed transaction does not match the prepared transaction")
+	}
+	authority := aucHouse.AuctionHouseData.Authority
+	for idx, signer := range signed.Transaction.Message.Signers() {
+		if !signer.Equals(authority) {
+			continue
+		}
+		if idx >= len(signed.Transaction.Signatures) || !signed.Transaction.Signatures[idx].Verify(authority, signedMessage) {
+			return nil, errors.Errorf("transaction is not signed by auction house authority %s", authority.String())
+		}
+		return aucHouse.Wm.SubmitPartialTransaction(signed)
+	}
+	return nil, errors.Errorf("auction house authority %s is not a signer of the transaction", authority.String())
+}
+
+// authoritySigns reports whether the authority account of trade instructions has
+// to sign, which is the case on auction houses that require sign-off.
+func (aucHouse *AuctionHouseActor) authoritySigns() bool {
+	return aucHouse.AuctionHouseData.RequiresSignOff
+}
+
+// send signs trades with AuthorityKey when the authority has to sign and is not
+// one of signers. Without AuthorityKey it returns a SignOffRequiredError.
+func (aucHouse *AuctionHouseActor) send(
+	feePayer solana.PublicKey,
+	instructions []solana.Instruction,
+	signers []solana.PrivateKey,
+) (*wallet_manager.OperationResult, error) {
+	if !aucHouse.needsSignOff(instructions, signers) {
+		return aucHouse.Wm.SendAndConfirmInstructions(feePayer, instructions, signers)
+	}
+	if aucHouse.AuthorityKey != nil {
+		if !aucHouse.AuthorityKey.PublicKey().Equals(aucHouse.AuctionHouseData.Authority) {
+			return nil, errors.Errorf("authority key %s does not match auction house authority %s", aucHouse.AuthorityKey.PublicKey().String(), aucHouse.AuctionHouseData.Authority.String())
+		}
+		return aucHouse.Wm.SendAndConfirmInstructions(feePayer, instructions, append(signers, aucHouse.AuthorityKey))
+	}
+	ptx, err := aucHouse.Wm.PrepareInstructions(feePayer, instructions, signers)
+	if err != nil {
+		return nil, err
+	}
+	// the sign-off may never come back, spending is reserved again when the
+	// signed transaction is submitted
+	ptx.Discard()
+	return nil, &SignOffRequiredError{Authority: aucHouse.AuctionHouseData.Authority, Transaction: ptx}
+}
+
+func (aucHouse *AuctionHouseActor) needsSignOff(instructions []solana.Instruction, signers []solana.PrivateKey) bool {
+	authority := aucHouse.AuctionHouseData.Authority
+	for _, signer := range signers {
+		if signer.PublicKey().Equals(authority) {
+			return false
+		}
+	}
+	for _, instruction := range instructions {
+		for _, account := range instruction.Accounts() {
+			if account.IsSigner && account.PublicKey.Equals(authority) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package auction_house
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/auction_house/auction_house_types"
+	"solana-go-wm/wallet_manager"
+	"testing"
+)
+
+func TestAuctionHouseActor_SignOff(t *testing.T) {
+	fake := newFakeCluster()
+	authority := solana.NewWallet().PrivateKey
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:       authority.PublicKey(),
+		TreasuryMint:    solana.SolMint,
+		RequiresSignOff: true,
+	})
+	seller := solana.NewWallet().PrivateKey
+	mint := solana.NewWallet().PublicKey()
+
+	_, err := actor.Sell(seller, mint, 1000, 1)
+	signOff, ok := err.(*SignOffRequiredError)
+	if !ok {
+		t.Fatalf("expected a sign-off required error, got %v", err)
+	}
+	if fake.LastSent() != nil {
+		t.Fatal("expected nothing to be sent before sign-off")
+	}
+	missing := signOff.Transaction.MissingSigners()
+	if len(missing) != 1 || !missing[0].Equals(authority.PublicKey()) {
+		t.Fatalf("expected only the authority signature to be missing, got %v", missing)
+	}
+
+	data, _ := signOff.Transaction.Marshal()
+	tampered, _ := wallet_manager.UnmarshalPartialTransaction(data)
+	tampered.Transaction.Message.RecentBlockhash = solana.Hash(solana.NewWallet().PublicKey())
+	if err = tampered.Sign(authority); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = actor.SubmitSignedOff(signOff.Transaction, tampered); err == nil {
+		t.Fatal("expected a changed transaction to be rejected")
+	}
+
+	signed, _ := wallet_manager.UnmarshalPartialTransaction(data)
+	if _, err = actor.SubmitSignedOff(signOff.Transaction, signed); err == nil {
+		t.Fatal("expected a transaction without the authority signature to be rejected")
+	}
+	if err = signed.Sign(authority); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = actor.SubmitSignedOff(signOff.Transaction, signed); err != nil {
+		t.Fatal(err)
+	}
+	if fake.LastSent() == nil {
+		t.Fatal("expected the co-signed transaction to be sent")
+	}
+
+	actor.AuthorityKey = authority
+	if _, err = actor.Sell(seller, mint, 2000, 1); err != nil {
+		t.Fatal(err)
+	}
+	tx := fake.LastSent()
+	if len(tx.Signatures) != 2 || !tx.IsSigner(authority.PublicKey()) {
+		t.Fatalf("expected seller and authority signatures, got %d", len(tx.Signatures))
+	}
+}
+
+func TestAuctionHouseActor_SignOffReleasesPolicy(t *testing.T) {
+	fake := newFakeCluster()
+	authority := solana.NewWallet().PrivateKey
+	actor := fake.actor(auction_house_types.AuctionHouse{
+		Authority:       authority.PublicKey(),
+		TreasuryMint:    solana.SolMint,
+		RequiresSignOff: true,
+	})
+	actor.Wm.Policy = wallet_manager.NewPolicyEngine(wallet_manager.SpendingPolicy{})
+	buyer := solana.NewWallet().PrivateKey
+	bid := AuctionHouseBidData{
+		MintAddress:  solana.NewWallet().PublicKey(),
+		TokenAccount: solana.NewWallet().PublicKey(),
+		Price:        700,
+		TokenSize:    1,
+	}
+
+	_, _, err := actor.PlaceBid(buyer, bid)
+	signOff, ok := err.(*SignOffRequiredError)
+	if !ok {
+		t.Fatalf("expected a sign-off required error, got %v", err)
+	}
+	buy := auction_house_types.NewBuyInstructionBuilder()
+	buy.AccountMetaSlice = signOff.Transaction.Transaction.Message.Instructions[0].ResolveInstructionAccounts(&signOff.Transaction.Transaction.Message)
+	if !buy.GetAuthorityAccount().PublicKey.Equals(authority.PublicKey()) || !buy.GetAuthorityAccount().IsSigner {
+		t.Fatalf("expected the authority to sign the buy, got %v", buy.AccountMetaSlice)
+	}
+	if spent := actor.Wm.Policy.SpentLamports(buyer.PublicKey()); spent != 0 {
+		t.Fatalf("a bid waiting for sign-off must not hold spending, spent %d", spent)
+	}
+
+	data, _ := signOff.Transaction.Marshal()
+	signed, _ := wallet_manager.UnmarshalPartialTransaction(data)
+	if err = signed.Sign(authority); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = actor.SubmitSignedOff(signOff.Transaction, signed); err != nil {
+		t.Fatal(err)
+	}
+	if spent := actor.Wm.Policy.SpentLamports(buyer.PublicKey()); spent != 700 {
+		t.Fatalf("expected the submitted bid to count as spending, spent %d", spent)
+	}
+}
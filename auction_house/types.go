@@ -10,6 +10,9 @@ type AuctionHouseActor struct {
 	Wm                  *solana_go_wm.WalletManager
 	AuctionHouseAccount solana.PublicKey
 	AuctionHouseData    auction_house_types.AuctionHouse
+	// AuthorityKey co-signs trades on auction houses that require sign-off. When it
+	// is not set those trades return a SignOffRequiredError instead.
+	AuthorityKey solana.PrivateKey
 }
 
 type AuctionHouseBuyData struct {
@@ -19,4 +22,89 @@ type AuctionHouseBuyData struct {
 	Price       uint64
 	TokenSize   uint64
 	Creators    []solana.PublicKey
+	Memo        string
+	SignMemo    bool
+}
+
+type AuctionHouseSellData struct {
+	MintAddress solana.PublicKey
+	Price       uint64
+	TokenSize   uint64
+	Memo        string
+	SignMemo    bool
+}
+
+type AuctionHouseBidData struct {
+	MintAddress  solana.PublicKey
+	TokenAccount solana.PublicKey
+	Price        uint64
+	TokenSize    uint64
+	PrintReceipt bool
+	Memo         string
+	SignMemo     bool
+}
+
+type AuctionHouseAcceptBidData struct {
+	Buyer       solana.PublicKey
+	MintAddress solana.PublicKey
+	Price       uint64
+	TokenSize   uint64
+	// Public marks bids placed with PublicBuy, whose trade state does not depend
+	// on the token account.
+	Public   bool
+	Creators []solana.PublicKey
+	Memo     string
+	SignMemo bool
+}
+
+type AuctionHouseBid struct {
+	Receipt    solana.PublicKey
+	TradeState solana.PublicKey
+	Buyer      solana.PublicKey
+	// TokenAccount is nil for public bids.
+	TokenAccount *solana.PublicKey
+	Price        uint64
+	TokenSize    uint64
+	CreatedAt    int64
+}
+
+func (bid AuctionHouseBid) IsPublic() bool {
+	return bid.TokenAccount == nil
+}
+
+type AuctionHouseListing struct {
+	// Receipt is zero for listings found from the seller's Sell transaction.
+	Receipt      solana.PublicKey
+	TradeState   solana.PublicKey
+	Seller       solana.PublicKey
+	TokenAccount solana.PublicKey
+	Price        uint64
+	TokenSize    uint64
+}
+
+// AuctionHouseCreateData describes a new auction house. The withdrawal
+// destinations default to the authority.
+type AuctionHouseCreateData struct {
+	TreasuryMint                       solana.PublicKey
+	SellerFeeBasisPoints               uint16
+	RequiresSignOff                    bool
+	CanChangeSalePrice                 bool
+	FeeWithdrawalDestination           solana.PublicKey
+	TreasuryWithdrawalDestinationOwner solana.PublicKey
+}
+
+// AuctionHouseUpdateData changes the settings that are set; nil fields and zero
+// keys keep the current value.
+type AuctionHouseUpdateData struct {
+	SellerFeeBasisPoints               *uint16
+	RequiresSignOff                    *bool
+	CanChangeSalePrice                 *bool
+	NewAuthority                       solana.PublicKey
+	FeeWithdrawalDestination           solana.PublicKey
+	TreasuryWithdrawalDestinationOwner solana.PublicKey
+}
+
+type AuctionHouseBalances struct {
+	FeeAccount uint64
+	Treasury   uint64
 }
@@ -0,0 +1,33 @@
+package atomicfile
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+)
+
+// Write replaces the file at path with data. The data is written to a temporary
+// file in the same directory first, so a crash leaves either the previous or the
+// new content on disk.
+func Write(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Errorf("failed to create temp file for %s. err: %s", path, err.Error())
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Errorf("failed to write %s. err: %s", path, err.Error())
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Errorf("failed to sync %s. err: %s", path, err.Error())
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
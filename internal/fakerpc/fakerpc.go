@@ -0,0 +1,292 @@
+// Package fakerpc is an in-memory JSON RPC client for tests. It keeps accounts,
+// records sent transactions and answers the calls WalletManager and the auction
+// house actor make; Handle overrides any method.
+package fakerpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+const (
+	Fee                  = 5000
+	TokenAccountLamports = 2039280
+)
+
+type Handler func(params []interface{}) (interface{}, error)
+
+type Client struct {
+	mu       sync.Mutex
+	accounts map[solana.PublicKey]*rpc.Account
+	handlers map[string]Handler
+	calls    []string
+	sent     []*solana.Transaction
+	history  []*solana.Transaction
+}
+
+func New() *Client {
+	return &Client{
+		accounts: map[solana.PublicKey]*rpc.Account{},
+		handlers: map[string]Handler{},
+	}
+}
+
+// Handle replaces the built-in answer of method.
+func (fake *Client) Handle(method string, handler Handler) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.handlers[method] = handler
+}
+
+func (fake *Client) Calls(method string) int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	cnt := 0
+	for _, call := range fake.calls {
+		if call == method {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+func (fake *Client) SetAccount(address, owner solana.PublicKey, lamports uint64, data []byte) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.accounts[address] = &rpc.Account{
+		Lamports: lamports,
+		Owner:    owner,
+		Data:     rpc.DataBytesOrJSONFromBytes(data),
+	}
+}
+
+func (fake *Client) SetTokenAccount(address, mint, owner solana.PublicKey, amount uint64) {
+	data := make([]byte, 165)
+	copy(data[0:32], mint.Bytes())
+	copy(data[32:64], owner.Bytes())
+	binary.LittleEndian.PutUint64(data[64:72], amount)
+	data[108] = 1
+	fake.SetAccount(address, solana.TokenProgramID, TokenAccountLamports, data)
+}
+
+// AddHistory makes tx returned by getSignaturesForAddress of its accounts.
+func (fake *Client) AddHistory(tx *solana.Transaction) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.history = append([]*solana.Transaction{tx}, fake.history...)
+}
+
+func (fake *Client) Sent() []*solana.Transaction {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]*solana.Transaction(nil), fake.sent...)
+}
+
+func (fake *Client) LastSent() *solana.Transaction {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.sent) == 0 {
+		return nil
+	}
+	return fake.sent[len(fake.sent)-1]
+}
+
+func (fake *Client) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	fake.mu.Lock()
+	fake.calls = append(fake.calls, method)
+	handler, ok := fake.handlers[method]
+	fake.mu.Unlock()
+	var result interface{}
+	var err error
+	if ok {
+		result, err = handler(params)
+	} else {
+		result, err = fake.call(method, params)
+	}
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (fake *Client) CallWithCallback(
+	ctx context.Context,
+	method string,
+	params []interface{},
+	callback func(*http.Request, *http.Response) error,
+) error {
+	return errors.Errorf("fake rpc: unexpected callback call %s", method)
+}
+
+func (fake *Client) call(method string, params []interface{}) (interface{}, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	switch method {
+	case "getAccountInfo":
+		return Value(encodeAccount(fake.accounts[params[0].(solana.PublicKey)])), nil
+	case "getMultipleAccounts":
+		var accounts []interface{}
+		for _, address := range params[0].([]solana.PublicKey) {
+			accounts = append(accounts, encodeAccount(fake.accounts[address]))
+		}
+		return Value(accounts), nil
+	case "getBalance":
+		var lamports uint64
+		if account := fake.accounts[params[0].(solana.PublicKey)]; account != nil {
+			lamports = account.Lamports
+		}
+		return Value(lamports), nil
+	case "getMinimumBalanceForRentExemption":
+		size, _ := params[0].(uint64)
+		return (size + 128) * 6960, nil
+	case "getProgramAccounts":
+		program := params[0].(solana.PublicKey)
+		filters, _ := params[1].(rpc.M)["filters"].([]rpc.RPCFilter)
+		var matched []interface{}
+		for address, account := range fake.accounts {
+			if account.Owner.Equals(program) && filtersMatch(account.Data.GetBinary(), filters) {
+				matched = append(matched, map[string]interface{}{
+					"pubkey":  address.String(),
+					"account": encodeAccount(account),
+				})
+			}
+		}
+		return matched, nil
+	case "getTokenLargestAccounts":
+		mint := params[0].(solana.PublicKey)
+		var holders []interface{}
+		for address, account := range fake.accounts {
+			data := account.Data.GetBinary()
+			if !account.Owner.Equals(solana.TokenProgramID) || len(data) != 165 || !solana.PublicKeyFromBytes(data[0:32]).Equals(mint) {
+				continue
+			}
+			holders = append(holders, map[string]interface{}{
+				"address":  address.String(),
+				"amount":   strconv.FormatUint(binary.LittleEndian.Uint64(data[64:72]), 10),
+				"decimals": 0,
+			})
+		}
+		return Value(holders), nil
+	case "getRecentBlockhash":
+		return Value(map[string]interface{}{
+			"blockhash":     solana.Hash{1}.String(),
+			"feeCalculator": map[string]interface{}{"lamportsPerSignature": Fee},
+		}), nil
+	case "getFeeForMessage":
+		return Value(Fee), nil
+	case "sendTransaction":
+		data, err := base64.StdEncoding.DecodeString(params[0].(string))
+		if err != nil {
+			return nil, err
+		}
+		tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+		if err != nil {
+			return nil, err
+		}
+		fake.sent = append(fake.sent, tx)
+		return tx.Signatures[0].String(), nil
+	case "getSignatureStatuses":
+		var statuses []interface{}
+		for range params[0].([]solana.Signature) {
+			statuses = append(statuses, map[string]interface{}{"slot": 1, "err": nil, "confirmationStatus": "confirmed"})
+		}
+		return Value(statuses), nil
+	case "getSignaturesForAddress":
+		address := params[0].(solana.PublicKey)
+		var signatures []interface{}
+		for _, tx := range fake.history {
+			for _, key := range tx.Message.AccountKeys {
+				if key.Equals(address) {
+					signatures = append(signatures, map[string]interface{}{"signature": tx.Signatures[0].String(), "slot": 1, "err": nil})
+					break
+				}
+			}
+		}
+		return signatures, nil
+	case "getTransaction":
+		signature := params[0].(solana.Signature)
+		for _, tx := range append(fake.history, fake.sent...) {
+			if tx.Signatures[0] == signature {
+				return encodeTransaction(tx)
+			}
+		}
+		return nil, nil
+	}
+	return nil, errors.Errorf("fake rpc: unexpected call %s", method)
+}
+
+// Value wraps value into the context envelope of RPC responses.
+func Value(value interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"context": map[string]interface{}{"slot": 1},
+		"value":   value,
+	}
+}
+
+func Account(lamports uint64, owner solana.PublicKey, data []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"lamports":   lamports,
+		"owner":      owner.String(),
+		"data":       []string{base64.StdEncoding.EncodeToString(data), "base64"},
+		"executable": false,
+		"rentEpoch":  0,
+	}
+}
+
+func encodeAccount(account *rpc.Account) interface{} {
+	if account == nil {
+		return nil
+	}
+	return Account(account.Lamports, account.Owner, account.Data.GetBinary())
+}
+
+// encodeTransaction answers getTransaction for tx with a meta that only charges
+// the fee to the fee payer.
+func encodeTransaction(tx *solana.Transaction) (interface{}, error) {
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]uint64, len(tx.Message.AccountKeys))
+	postBalances := make([]uint64, len(tx.Message.AccountKeys))
+	balances[0] = Fee
+	return map[string]interface{}{
+		"slot":        1,
+		"transaction": []string{encoded, "base64"},
+		"meta": map[string]interface{}{
+			"err":          nil,
+			"fee":          Fee,
+			"preBalances":  balances,
+			"postBalances": postBalances,
+		},
+	}, nil
+}
+
+func filtersMatch(data []byte, filters []rpc.RPCFilter) bool {
+	for _, filter := range filters {
+		if filter.DataSize != 0 && uint64(len(data)) != filter.DataSize {
+			return false
+		}
+		if memcmp := filter.Memcmp; memcmp != nil {
+			end := memcmp.Offset + uint64(len(memcmp.Bytes))
+			if end > uint64(len(data)) || !bytes.Equal(data[memcmp.Offset:end], memcmp.Bytes) {
+				return false
+			}
+		}
+	}
+	return true
+}
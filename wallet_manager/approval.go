@@ -0,0 +1,449 @@
+package wallet_manager
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"os"
+	"solana-go-wm/internal/atomicfile"
+	"sort"
+	"sync"
+	"time"
+)
+
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending   ApprovalStatus = "pending"
+	ApprovalStatusApproved  ApprovalStatus = "approved"
+	ApprovalStatusRejected  ApprovalStatus = "rejected"
+	ApprovalStatusExecuting ApprovalStatus = "executing"
+	ApprovalStatusExecuted  ApprovalStatus = "executed"
+	ApprovalStatusFailed    ApprovalStatus = "failed"
+)
+
+// ApprovalPolicy defines which transactions need sign-off. Thresholds are compared
+// with the total amount a transaction moves per mint; zero disables a threshold.
+// Approvers are the base58 public keys allowed to sign approvals.
+type ApprovalPolicy struct {
+	LamportsThreshold uint64            `json:"lamportsThreshold"`
+	MintThresholds    map[string]uint64 `json:"mintThresholds"`
+	Approvers         []string          `json:"approvers"`
+	Quorum            int               `json:"quorum"`
+}
+
+type ApprovalEvent struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Action  string    `json:"action"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+// Approval is the signature of an approver over ApprovalMessage of a request.
+type Approval struct {
+	Approver  string `json:"approver"`
+	Signature string `json:"signature"`
+}
+
+// ApprovalRequest is a transaction waiting for sign-off. RequesterKey is the base58
+// public key of the requester, who can not approve the request. A request queued
+// under an idempotency key is executed under the same key.
+type ApprovalRequest struct {
+	ID             string          `json:"id"`
+	Status         ApprovalStatus  `json:"status"`
+	RequestedBy    string          `json:"requestedBy"`
+	RequesterKey   string          `json:"requesterKey"`
+	IdempotencyKey string          `json:"idempotencyKey,omitempty"`
+	Transaction    string          `json:"transaction"`
+	Summary        string          `json:"summary"`
+	Approvals      []Approval      `json:"approvals"`
+	Signature      string          `json:"signature,omitempty"`
+	Events         []ApprovalEvent `json:"events"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// ApprovalMessage returns the bytes an approver signs. It binds the request ID to
+// the stored transaction, so a signature can not approve anything else.
+func ApprovalMessage(request ApprovalRequest) []byte {
+	hash := sha256.Sum256([]byte(request.Transaction))
+	return []byte(fmt.Sprintf("approve request %s transaction %s", request.ID, hex.EncodeToString(hash[:])))
+}
+
+func SignApproval(request ApprovalRequest, approver solana.PrivateKey) (solana.Signature, error) {
+	return approver.Sign(ApprovalMessage(request))
+}
+
+type ApprovalRequiredError struct {
+	RequestID string
+}
+
+func (e *ApprovalRequiredError) Error() string {
+	return fmt.Sprintf("transaction requires approval, request %s", e.RequestID)
+}
+
+// ApprovalQueue stores transactions waiting for sign-off in a local JSON file
+// together with the audit trail of every request.
+type ApprovalQueue struct {
+	Policy ApprovalPolicy
+
+	path     string
+	mu       sync.Mutex
+	requests map[string]*ApprovalRequest
+}
+
+func OpenApprovalQueue(path string, policy ApprovalPolicy) (*ApprovalQueue, error) {
+	queue := &ApprovalQueue{
+		Policy:   policy,
+		path:     path,
+		requests: map[string]*ApprovalRequest{},
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return queue, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("failed to read approval queue %s. err: %s", path, err.Error())
+	}
+	var requests []*ApprovalRequest
+	if err = json.Unmarshal(data, &requests); err != nil {
+		return nil, errors.Errorf("failed to parse approval queue %s. err: %s", path, err.Error())
+	}
+	for _, request := range requests {
+		queue.requests[request.ID] = request
+	}
+	return queue, nil
+}
+
+func (queue *ApprovalQueue) RequiresApproval(actions []PolicyAction) bool {
+	totals := map[solana.PublicKey]uint64{}
+	for _, action := range actions {
+		mint := action.Mint
+		if action.IsLamports() {
+			mint = solana.PublicKey{}
+		}
+		totals[mint] += action.Amount
+	}
+	for mint, total := range totals {
+		threshold := queue.Policy.LamportsThreshold
+		if !mint.IsZero() {
+			threshold = queue.Policy.MintThresholds[mint.String()]
+		}
+		if threshold > 0 && total >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// Submit queues tx for approval. A request that is still open under the same
+// idempotency key is returned instead of queueing the transaction again.
+func (queue *ApprovalQueue) Submit(
+	tx *solana.Transaction,
+	requestedBy string,
+	requesterKey solana.PublicKey,
+	idempotencyKey string,
+) (ApprovalRequest, error) {
+	if len(tx.Signatures) == 0 {
+		tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	}
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		return ApprovalRequest{}, err
+	}
+	idBytes := make([]byte, 16)
+	if _, err = rand.Read(idBytes); err != nil {
+		return ApprovalRequest{}, err
+	}
+	now := time.Now().UTC()
+	request := &ApprovalRequest{
+		ID:             hex.EncodeToString(idBytes),
+		Status:         ApprovalStatusPending,
+		RequestedBy:    requestedBy,
+		RequesterKey:   requesterKey.String(),
+		IdempotencyKey: idempotencyKey,
+		Transaction:    encoded,
+		Summary:        SummarizeTransaction(tx),
+		Events:         []ApprovalEvent{{Time: now, Actor: requestedBy, Action: "requested"}},
+		CreatedAt:      now,
+	}
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if open, ok := queue.openRequest(idempotencyKey); ok {
+		return *open, nil
+	}
+	queue.requests[request.ID] = request
+	if err = queue.flush(); err != nil {
+		delete(queue.requests, request.ID)
+		return ApprovalRequest{}, err
+	}
+	return *request, nil
+}
+
+func (queue *ApprovalQueue) Get(id string) (ApprovalRequest, bool) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	request, ok := queue.requests[id]
+	if !ok {
+		return ApprovalRequest{}, false
+	}
+	return *request, true
+}
+
+func (queue *ApprovalQueue) List(status ApprovalStatus) []ApprovalRequest {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	var requests []ApprovalRequest
+	for _, request := range queue.sortedRequests() {
+		if status == "" || request.Status == status {
+			requests = append(requests, *request)
+		}
+	}
+	return requests
+}
+
+func (queue *ApprovalQueue) Approve(id string, approver solana.PublicKey, signature solana.Signature, comment string) (ApprovalRequest, error) {
+	name := approver.String()
+	return queue.update(id, name, func(request *ApprovalRequest) error {
+		if request.Status != ApprovalStatusPending {
+			return errors.Errorf("request %s is %s", id, request.Status)
+		}
+		if !queue.isApprover(approver) {
+			return errors.Errorf("%s is not an approver", name)
+		}
+		if name == request.RequesterKey {
+			return errors.Errorf("%s cannot approve own request", name)
+		}
+		for _, approval := range request.Approvals {
+			if approval.Approver == name {
+				return errors.Errorf("%s already approved request %s", name, id)
+			}
+		}
+		if !signature.Verify(approver, ApprovalMessage(*request)) {
+			return errors.Errorf("invalid approval signature of %s", name)
+		}
+		request.Approvals = append(request.Approvals, Approval{Approver: name, Signature: signature.String()})
+		request.Events = append(request.Events, ApprovalEvent{Time: time.Now().UTC(), Actor: name, Action: "approved", Comment: comment})
+		if len(request.Approvals) >= queue.quorum() {
+			request.Status = ApprovalStatusApproved
+		}
+		return nil
+	})
+}
+
+func (queue *ApprovalQueue) Reject(id string, approver solana.PublicKey, reason string) (ApprovalRequest, error) {
+	name := approver.String()
+	return queue.update(id, name, func(request *ApprovalRequest) error {
+		if request.Status != ApprovalStatusPending && request.Status != ApprovalStatusApproved {
+			return errors.Errorf("request %s is %s", id, request.Status)
+		}
+		if !queue.isApprover(approver) {
+			return errors.Errorf("%s is not an approver", name)
+		}
+		request.Status = ApprovalStatusRejected
+		request.Events = append(request.Events, ApprovalEvent{Time: time.Now().UTC(), Actor: name, Action: "rejected", Comment: reason})
+		return nil
+	})
+}
+
+// claim moves an approved request to executing, so it is signed and sent only once
+// even when several callers execute it at the same time.
+func (queue *ApprovalQueue) claim(id, actor string) (ApprovalRequest, error) {
+	return queue.update(id, actor, func(request *ApprovalRequest) error {
+		if request.Status != ApprovalStatusApproved {
+			return errors.Errorf("request %s is %s", id, request.Status)
+		}
+		request.Status = ApprovalStatusExecuting
+		request.Events = append(request.Events, ApprovalEvent{Time: time.Now().UTC(), Actor: actor, Action: string(ApprovalStatusExecuting)})
+		return nil
+	})
+}
+
+func (queue *ApprovalQueue) record(id, actor string, status ApprovalStatus, signature, comment string) error {
+	_, err := queue.update(id, actor, func(request *ApprovalRequest) error {
+		request.Status = status
+		request.Signature = signature
+		request.Events = append(request.Events, ApprovalEvent{Time: time.Now().UTC(), Actor: actor, Action: string(status), Comment: comment})
+		return nil
+	})
+	return err
+}
+
+func (queue *ApprovalQueue) update(id, actor string, change func(request *ApprovalRequest) error) (ApprovalRequest, error) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	request, ok := queue.requests[id]
+	if !ok {
+		return ApprovalRequest{}, errors.Errorf("approval request %s not found", id)
+	}
+	previous := *request
+	previous.Approvals = append([]Approval(nil), request.Approvals...)
+	previous.Events = append([]ApprovalEvent(nil), request.Events...)
+	if err := change(request); err != nil {
+		return ApprovalRequest{}, err
+	}
+	if err := queue.flush(); err != nil {
+		*request = previous
+		return ApprovalRequest{}, err
+	}
+	return *request, nil
+}
+
+// openRequest returns the request queued under idempotencyKey that has not been
+// rejected or failed.
+func (queue *ApprovalQueue) openRequest(idempotencyKey string) (*ApprovalRequest, bool) {
+	if idempotencyKey == "" {
+		return nil, false
+	}
+	for _, request := range queue.requests {
+		if request.IdempotencyKey == idempotencyKey &&
+			request.Status != ApprovalStatusRejected && request.Status != ApprovalStatusFailed {
+			return request, true
+		}
+	}
+	return nil, false
+}
+
+func (queue *ApprovalQueue) isApprover(key solana.PublicKey) bool {
+	for _, approver := range queue.Policy.Approvers {
+		if approver == key.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (queue *ApprovalQueue) quorum() int {
+	if queue.Policy.Quorum < 1 {
+		return 1
+	}
+	return queue.Policy.Quorum
+}
+
+func (queue *ApprovalQueue) sortedRequests() []*ApprovalRequest {
+	var requests []*ApprovalRequest
+	for _, request := range queue.requests {
+		requests = append(requests, request)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if !requests[i].CreatedAt.Equal(requests[j].CreatedAt) {
+			return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+		}
+		return requests[i].ID < requests[j].ID
+	})
+	return requests
+}
+
+func (queue *ApprovalQueue) flush() error {
+	data, err := json.MarshalIndent(queue.sortedRequests(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(queue.path, data)
+}
+
+// ExecuteApproved signs and sends a request once it reached quorum. The request is
+// marked executing before it is signed, so it can not be sent twice. The stored
+// transaction gets a fresh blockhash, the instructions stay exactly as approved.
+// Without signers a fully signed transaction is sent as it was queued. Requests
+// queued under an idempotency key are sent under that key.
+func (wm *WalletManager) ExecuteApproved(id string, signers []solana.PrivateKey) (*OperationResult, error) {
+	if wm.Approvals == nil {
+		return nil, errors.New("approval queue is not configured")
+	}
+	var request ApprovalRequest
+	var err error
+	if wm.IsDryRun() {
+		var ok bool
+		request, ok = wm.Approvals.Get(id)
+		if !ok {
+			return nil, errors.Errorf("approval request %s not found", id)
+		}
+		if request.Status != ApprovalStatusApproved {
+			return nil, errors.Errorf("request %s is %s", id, request.Status)
+		}
+	} else if request, err = wm.Approvals.claim(id, wm.Operator); err != nil {
+		return nil, err
+	}
+	approved := *wm
+	if request.IdempotencyKey != "" {
+		approved.idempotencyKey = request.IdempotencyKey
+		approved.idempotencyReserved = false
+	}
+	approved.approvalChecked = true
+	result, err := approved.sendApproved(request, signers)
+	if result != nil && result.Simulated {
+		return result, err
+	}
+	if unconfirmed, ok := errors.Cause(err).(*UnconfirmedError); ok {
+		// the transaction may have landed, executing the request again could pay twice
+		if recordErr := wm.Approvals.record(id, wm.Operator, ApprovalStatusExecuted, unconfirmed.Signature.String(), err.Error()); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		if recordErr := wm.Approvals.record(id, wm.Operator, ApprovalStatusFailed, "", err.Error()); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+	if err = wm.Approvals.record(id, wm.Operator, ApprovalStatusExecuted, result.Signature().String(), ""); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func (wm *WalletManager) sendApproved(request ApprovalRequest, signers []solana.PrivateKey) (*OperationResult, error) {
+	tx, err := decodeBase64Transaction(request.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	if len(signers) == 0 && len(MissingSigners(tx)) == 0 {
+		return wm.SendAndConfirmTransaction(tx)
+	}
+	recent, err := wm.Client.GetRecentBlockhash(wm.Context, wm.Commitment)
+	if err != nil {
+		return nil, err
+	}
+	tx.Message.RecentBlockhash = recent.Value.Blockhash
+	tx.Signatures = nil
+	return wm.signAndSend(tx, signers)
+}
+
+// submitForApproval queues tx when it moves more than the approval thresholds and
+// returns an ApprovalRequiredError then. Otherwise it returns a copy of wm that
+// sends tx without checking again. Transactions whose idempotency key is already
+// journaled are not queued, sending them replays the journaled result.
+func (wm *WalletManager) submitForApproval(tx *solana.Transaction) (*WalletManager, error) {
+	checked := *wm
+	checked.approvalChecked = true
+	if wm.Approvals == nil || wm.IsDryRun() {
+		return &checked, nil
+	}
+	if wm.idempotencyKey != "" && wm.Journal != nil {
+		if entry, ok := wm.Journal.Get(wm.idempotencyKey); ok && entry.Status != JournalStatusExpired {
+			return &checked, nil
+		}
+	}
+	actions, err := wm.DecodePolicyActions(tx)
+	if err != nil {
+		return nil, err
+	}
+	if !wm.Approvals.RequiresApproval(actions) {
+		return &checked, nil
+	}
+	// the requester is bound to a key, the operator name is only informational
+	requester := wm.OperatorKey
+	if requester.IsZero() {
+		requester = tx.Message.AccountKeys[0]
+	}
+	request, err := wm.Approvals.Submit(tx, wm.Operator, requester, wm.idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	return nil, &ApprovalRequiredError{RequestID: request.ID}
+}
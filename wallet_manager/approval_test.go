@@ -0,0 +1,270 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"path/filepath"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+	"time"
+)
+
+func TestWalletManager_ApprovalQuorum(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	manager.Journal = nil
+	alice := solana.NewWallet().PrivateKey
+	bob := solana.NewWallet().PrivateKey
+	queue, err := OpenApprovalQueue(filepath.Join(t.TempDir(), "approvals.json"), ApprovalPolicy{
+		LamportsThreshold: 1000000,
+		Approvers:         []string{alice.PublicKey().String(), bob.PublicKey().String()},
+		Quorum:            1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.Approvals = queue
+	manager.Operator = "alice"
+	manager.OperatorKey = alice.PublicKey()
+	from := solana.NewWallet().PrivateKey
+	to := solana.NewWallet().PublicKey()
+
+	if _, err = manager.SendLamports(from, to, 999999); err != nil {
+		t.Fatalf("transfer below threshold should not need approval. err: %s", err)
+	}
+	_, err = manager.SendLamports(from, to, 1000000)
+	var required *ApprovalRequiredError
+	if !errors.As(err, &required) {
+		t.Fatalf("expected approval to be required, got %v", err)
+	}
+	if fake.Calls("sendTransaction") != 1 {
+		t.Fatal("transfer above threshold must not be sent before approval")
+	}
+	if _, err = manager.ExecuteApproved(required.RequestID, []solana.PrivateKey{from}); err == nil {
+		t.Fatal("pending request must not be executed")
+	}
+	request, _ := queue.Get(required.RequestID)
+	aliceSignature, _ := SignApproval(request, alice)
+	if _, err = queue.Approve(required.RequestID, alice.PublicKey(), aliceSignature, ""); err == nil {
+		t.Fatal("requester must not approve own request")
+	}
+	bobSignature, _ := SignApproval(request, bob)
+	if _, err = queue.Approve(required.RequestID, alice.PublicKey(), bobSignature, ""); err == nil {
+		t.Fatal("a signature of another key must not approve")
+	}
+	request, err = queue.Approve(required.RequestID, bob.PublicKey(), bobSignature, "checked invoice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Status != ApprovalStatusApproved {
+		t.Fatalf("expected approved status, got %s", request.Status)
+	}
+
+	result, err := manager.ExecuteApproved(required.RequestID, []solana.PrivateKey{from})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = manager.ExecuteApproved(required.RequestID, []solana.PrivateKey{from}); err == nil {
+		t.Fatal("executed request must not be executed again")
+	}
+	if fake.Calls("sendTransaction") != 2 {
+		t.Fatalf("expected the approved transfer to be sent once, got %d sends", fake.Calls("sendTransaction"))
+	}
+	reopened, err := OpenApprovalQueue(queue.path, queue.Policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ = reopened.Get(required.RequestID)
+	if request.Status != ApprovalStatusExecuted || request.Signature != result.Signature().String() || len(request.Events) != 4 {
+		t.Fatalf("unexpected audit trail %+v", request)
+	}
+}
+
+func TestApprovalQueue_Approve(t *testing.T) {
+	alice := solana.NewWallet().PrivateKey
+	bob := solana.NewWallet().PrivateKey
+	queue, err := OpenApprovalQueue(filepath.Join(t.TempDir(), "approvals.json"), ApprovalPolicy{
+		Approvers: []string{alice.PublicKey().String(), bob.PublicKey().String()},
+		Quorum:    2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := solana.NewWallet().PrivateKey
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{makeTransferInstruction(from.PublicKey(), solana.NewWallet().PublicKey(), 1)},
+		solana.Hash{1},
+		solana.TransactionPayer(from.PublicKey()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := queue.Submit(tx, "operator", from.PublicKey(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := queue.Submit(tx, "operator", from.PublicKey(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outsider := solana.NewWallet().PrivateKey
+	signature, _ := SignApproval(request, outsider)
+	if _, err = queue.Approve(request.ID, outsider.PublicKey(), signature, ""); err == nil {
+		t.Fatal("a key outside the approver set must not approve")
+	}
+	otherSignature, _ := SignApproval(other, alice)
+	if _, err = queue.Approve(request.ID, alice.PublicKey(), otherSignature, ""); err == nil {
+		t.Fatal("a signature for another request must not approve")
+	}
+	signature, _ = SignApproval(request, alice)
+	if _, err = queue.Approve(request.ID, alice.PublicKey(), signature, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = queue.Approve(request.ID, alice.PublicKey(), signature, ""); err == nil {
+		t.Fatal("a key must not approve twice")
+	}
+	request, _ = queue.Get(request.ID)
+	if request.Status != ApprovalStatusPending || len(request.Approvals) != 1 {
+		t.Fatalf("expected one of two approvals, got %+v", request)
+	}
+	signature, _ = SignApproval(request, bob)
+	if request, err = queue.Approve(request.ID, bob.PublicKey(), signature, ""); err != nil {
+		t.Fatal(err)
+	}
+	if request.Status != ApprovalStatusApproved {
+		t.Fatalf("expected approved status, got %s", request.Status)
+	}
+}
+
+func newTestApprovalQueue(t *testing.T, approvers ...solana.PrivateKey) *ApprovalQueue {
+	policy := ApprovalPolicy{LamportsThreshold: 1000, Quorum: 1}
+	for _, approver := range approvers {
+		policy.Approvers = append(policy.Approvers, approver.PublicKey().String())
+	}
+	queue, err := OpenApprovalQueue(filepath.Join(t.TempDir(), "approvals.json"), policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return queue
+}
+
+func approveRequest(t *testing.T, queue *ApprovalQueue, id string, approver solana.PrivateKey) {
+	request, _ := queue.Get(id)
+	signature, err := SignApproval(request, approver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = queue.Approve(id, approver.PublicKey(), signature, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWalletManager_ApprovalGatesSubmittedTransactions(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	manager.Journal = nil
+	bob := solana.NewWallet().PrivateKey
+	manager.Approvals = newTestApprovalQueue(t, bob)
+	from := solana.NewWallet().PrivateKey
+	tx, err := manager.BuildTransaction(from.PublicKey(), []solana.Instruction{makeTransferInstruction(from.PublicKey(), solana.NewWallet().PublicKey(), 5000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = SignTransaction(tx, []solana.PrivateKey{from}); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ExportTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = manager.SubmitExportedTransaction(exported)
+	var required *ApprovalRequiredError
+	if !errors.As(err, &required) {
+		t.Fatalf("expected approval to be required, got %v", err)
+	}
+	if fake.Calls("sendTransaction") != 0 {
+		t.Fatal("transaction signed elsewhere must not be sent before approval")
+	}
+	request, _ := manager.Approvals.Get(required.RequestID)
+	if request.RequesterKey != from.PublicKey().String() {
+		t.Fatalf("expected the fee payer to be the requester without an operator key, got %s", request.RequesterKey)
+	}
+
+	approveRequest(t, manager.Approvals, required.RequestID, bob)
+	result, err := manager.ExecuteApproved(required.RequestID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Signature() != tx.Signatures[0] || fake.Calls("sendTransaction") != 1 {
+		t.Fatalf("expected the signed transaction to be sent as queued, got %s", result.Signature())
+	}
+}
+
+func TestWalletManager_ApprovalWithIdempotencyKey(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	bob := solana.NewWallet().PrivateKey
+	manager.Approvals = newTestApprovalQueue(t, bob)
+	keyed := manager.WithIdempotencyKey("payout-1")
+	from := solana.NewWallet().PrivateKey
+	to := solana.NewWallet().PublicKey()
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		_, err := keyed.SendLamports(from, to, 5000)
+		var required *ApprovalRequiredError
+		if !errors.As(err, &required) {
+			t.Fatalf("expected approval to be required, got %v", err)
+		}
+		ids = append(ids, required.RequestID)
+	}
+	if ids[0] != ids[1] || len(manager.Approvals.List("")) != 1 {
+		t.Fatalf("a retry must not queue the transfer again, got requests %v", ids)
+	}
+	request, _ := manager.Approvals.Get(ids[0])
+	if request.IdempotencyKey != "payout-1" {
+		t.Fatalf("expected the idempotency key to be stored, got %q", request.IdempotencyKey)
+	}
+
+	approveRequest(t, manager.Approvals, ids[0], bob)
+	if _, err := manager.ExecuteApproved(ids[0], []solana.PrivateKey{from}); err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := manager.Journal.Get("payout-1"); !ok || entry.Status != JournalStatusConfirmed {
+		t.Fatalf("expected the approved transfer to be journaled under its key, got %+v", entry)
+	}
+	result, err := keyed.SendLamports(from, to, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Replayed || fake.Calls("sendTransaction") != 1 || len(manager.Approvals.List("")) != 1 {
+		t.Fatalf("expected the executed transfer to be replayed, got %+v", result)
+	}
+}
+
+func TestWalletManager_ExecuteApprovedUnconfirmed(t *testing.T) {
+	fake := fakerpc.New()
+	fake.Handle("getSignatureStatuses", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value([]interface{}{
+			map[string]interface{}{"slot": 5, "err": nil, "confirmationStatus": "processed"},
+		}), nil
+	})
+	manager := newFakeWalletManager(fake)
+	manager.ConfirmationTimeout = 50 * time.Millisecond
+	bob := solana.NewWallet().PrivateKey
+	manager.Approvals = newTestApprovalQueue(t, bob)
+	from := solana.NewWallet().PrivateKey
+
+	_, err := manager.SendLamports(from, solana.NewWallet().PublicKey(), 5000)
+	var required *ApprovalRequiredError
+	if !errors.As(err, &required) {
+		t.Fatalf("expected approval to be required, got %v", err)
+	}
+	approveRequest(t, manager.Approvals, required.RequestID, bob)
+	if _, err = manager.ExecuteApproved(required.RequestID, []solana.PrivateKey{from}); !IsUnconfirmed(err) {
+		t.Fatalf("expected an unconfirmed transfer, got %v", err)
+	}
+	request, _ := manager.Approvals.Get(required.RequestID)
+	if request.Status != ApprovalStatusExecuted || request.Signature != fake.LastSent().Signatures[0].String() {
+		t.Fatalf("a sent transfer must be recorded as executed, got %+v", request)
+	}
+}
@@ -0,0 +1,20 @@
+package wallet_manager
+
+import (
+	"context"
+	"github.com/gagliardetto/solana-go/rpc"
+	"solana-go-wm/internal/fakerpc"
+	"time"
+)
+
+func newFakeWalletManager(fake *fakerpc.Client) *WalletManager {
+	return NewWalletManagerWithOpts(
+		context.TODO(),
+		rpc.NewWithCustomRPCClient(fake),
+		rpc.CommitmentConfirmed,
+		rpc.ConfirmationStatusConfirmed,
+		time.Second,
+		10*time.Millisecond,
+		false,
+	)
+}
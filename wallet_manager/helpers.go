@@ -1,6 +1,12 @@
 package wallet_manager
 
-import "github.com/gagliardetto/solana-go"
+import (
+	"encoding/base64"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/pkg/errors"
+)
 
 func appendSignerIfNotPresented(signers []solana.PrivateKey, newSigner solana.PrivateKey) []solana.PrivateKey {
 	for _, signer := range signers {
@@ -10,3 +16,30 @@ func appendSignerIfNotPresented(signers []solana.PrivateKey, newSigner solana.Pr
 	}
 	return append(signers, newSigner)
 }
+
+func decodeBase64Transaction(encoded string) (*solana.Transaction, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return solana.TransactionFromDecoder(bin.NewBinDecoder(raw))
+}
+
+func copyTransaction(tx *solana.Transaction) (*solana.Transaction, error) {
+	raw, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	txCopy := &solana.Transaction{Signatures: append([]solana.Signature(nil), tx.Signatures...)}
+	if err = txCopy.Message.UnmarshalWithDecoder(bin.NewBinDecoder(raw)); err != nil {
+		return nil, err
+	}
+	return txCopy, nil
+}
+
+// isRejectedSend reports whether the node refused to send a transaction, in which
+// case it never reached the cluster.
+func isRejectedSend(err error) bool {
+	_, rejected := errors.Cause(err).(*jsonrpc.RPCError)
+	return rejected
+}
@@ -0,0 +1,59 @@
+package wallet_manager
+
+import (
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+)
+
+type HistoryEntry struct {
+	Signature solana.Signature
+	Slot      uint64
+	BlockTime *solana.UnixTimeSeconds
+	Err       interface{}
+	Memos     []string
+}
+
+func (wm *WalletManager) GetHistory(account solana.PublicKey, limit int) ([]HistoryEntry, error) {
+	signatures, err := wm.Client.GetSignaturesForAddressWithOpts(wm.Context, account, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: wm.Commitment,
+	})
+	if err != nil {
+		return nil, errors.Errorf("failed to get signatures of %s. err: %s", account.String(), err.Error())
+	}
+	var entries []HistoryEntry
+	for _, signature := range signatures {
+		entry, err := wm.GetHistoryEntry(signature.Signature)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (wm *WalletManager) GetHistoryEntry(signature solana.Signature) (HistoryEntry, error) {
+	result, err := wm.Client.GetTransaction(wm.Context, signature, &rpc.GetTransactionOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: wm.Commitment,
+	})
+	if err != nil {
+		return HistoryEntry{}, errors.Errorf("failed to get transaction %s. err: %s", signature.String(), err.Error())
+	}
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(result.Transaction.GetBinary()))
+	if err != nil {
+		return HistoryEntry{}, errors.Errorf("failed to decode transaction %s. err: %s", signature.String(), err.Error())
+	}
+	entry := HistoryEntry{
+		Signature: signature,
+		Slot:      result.Slot,
+		BlockTime: result.BlockTime,
+		Memos:     ParseMemos(tx),
+	}
+	if result.Meta != nil {
+		entry.Err = result.Meta.Err
+	}
+	return entry, nil
+}
@@ -0,0 +1,218 @@
+package wallet_manager
+
+import (
+	"fmt"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+)
+
+// WithIdempotencyKey returns a copy of wm whose next transaction is recorded in
+// wm.Journal under key before it is sent. Calling an operation again with the same
+// key returns the recorded result instead of executing it twice.
+func (wm *WalletManager) WithIdempotencyKey(key string) *WalletManager {
+	keyed := *wm
+	keyed.idempotencyKey = key
+	return &keyed
+}
+
+// ReconcileJournal checks every pending journal entry against the chain. Entries
+// that landed are marked confirmed or failed, entries whose blockhash expired are
+// marked expired and entries that may still land are resubmitted.
+func (wm *WalletManager) ReconcileJournal() ([]JournalEntry, error) {
+	if wm.Journal == nil {
+		return nil, errors.New("journal is not configured")
+	}
+	var entries []JournalEntry
+	for _, entry := range wm.Journal.Pending() {
+		reconciled, err := wm.reconcileJournalEntry(entry)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, reconciled)
+	}
+	return entries, nil
+}
+
+// reserveIdempotencyKey claims the idempotency key of wm in the journal and returns
+// a copy of wm holding the reservation.
+func (wm *WalletManager) reserveIdempotencyKey() (*WalletManager, func(), error) {
+	if wm.Journal == nil {
+		return nil, nil, errors.New("idempotency key is set but journal is not configured")
+	}
+	release, err := wm.Journal.Reserve(wm.idempotencyKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	reserved := *wm
+	reserved.idempotencyReserved = true
+	return &reserved, release, nil
+}
+
+func (wm *WalletManager) sendJournaled(tx *solana.Transaction) (*OperationResult, error) {
+	if !wm.idempotencyReserved {
+		reserved, release, err := wm.reserveIdempotencyKey()
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return reserved.sendJournaled(tx)
+	}
+	key := wm.idempotencyKey
+	entry, exists := wm.Journal.Get(key)
+	if exists && entry.Status == JournalStatusPending {
+		var err error
+		entry, err = wm.reconcileJournalEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if exists {
+		var result *OperationResult
+		var err error
+		switch entry.Status {
+		case JournalStatusConfirmed:
+			result, err = wm.journalEntryResult(entry)
+		case JournalStatusFailed:
+			return nil, errors.Wrapf(ErrIdempotencyKeyFailed, "key %s: %s", key, entry.Error)
+		case JournalStatusPending:
+			result, err = wm.awaitJournalEntry(entry)
+		}
+		if result != nil {
+			result.Replayed = true
+		}
+		if entry.Status != JournalStatusExpired {
+			return result, err
+		}
+	}
+
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		return nil, err
+	}
+	next := JournalEntry{
+		Key:         key,
+		Signature:   tx.Signatures[0].String(),
+		Transaction: encoded,
+		Status:      JournalStatusPending,
+		Attempts:    entry.Attempts + 1,
+		CreatedAt:   entry.CreatedAt,
+	}
+	if err = wm.Journal.Put(next); err != nil {
+		return nil, errors.Errorf("failed to journal transaction %s. err: %s", key, err.Error())
+	}
+	_, err = wm.Client.SendTransactionWithOpts(wm.Context, tx, rpc.TransactionOpts{
+		SkipPreflight:       wm.SkipPreflight,
+		PreflightCommitment: wm.Commitment,
+	})
+	if isRejectedSend(err) {
+		// the node refused the transaction, so it never reached the cluster
+		// and must not be resubmitted by reconciliation
+		next.Status = JournalStatusFailed
+		next.Error = err.Error()
+		if putErr := wm.Journal.Put(next); putErr != nil {
+			return nil, errors.Errorf("failed to journal transaction %s. err: %s", key, putErr.Error())
+		}
+		return nil, err
+	}
+	wm.markPolicySent()
+	if err != nil {
+		// the transaction may still have reached the cluster, keep the entry
+		// pending so reconciliation decides whether it landed
+		return nil, &UnconfirmedError{Signature: tx.Signatures[0], Err: err}
+	}
+	return wm.awaitJournalEntry(next)
+}
+
+func (wm *WalletManager) awaitJournalEntry(entry JournalEntry) (*OperationResult, error) {
+	signature, err := solana.SignatureFromBase58(entry.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, awaitErr := wm.awaitSignaturesConfirmation([]solana.Signature{signature})
+	entry, err = wm.reconcileJournalEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	switch entry.Status {
+	case JournalStatusConfirmed:
+		return wm.journalEntryResult(entry)
+	case JournalStatusFailed:
+		return nil, errors.Wrapf(ErrIdempotencyKeyFailed, "key %s: %s", entry.Key, entry.Error)
+	case JournalStatusExpired:
+		return nil, errors.Errorf("transaction %s expired before landing", entry.Signature)
+	}
+	if awaitErr == nil {
+		awaitErr = errors.New("transaction is not confirmed yet")
+	}
+	return nil, &UnconfirmedError{Signature: signature, Err: awaitErr}
+}
+
+func (wm *WalletManager) reconcileJournalEntry(entry JournalEntry) (JournalEntry, error) {
+	tx, err := decodeJournalTransaction(entry)
+	if err != nil {
+		return entry, err
+	}
+	statuses, err := wm.Client.GetSignatureStatuses(wm.Context, true, tx.Signatures[0])
+	if err != nil {
+		return entry, errors.Errorf("failed to get status of %s. err: %s", entry.Signature, err.Error())
+	}
+	var status *rpc.SignatureStatusesResult
+	if len(statuses.Value) > 0 {
+		status = statuses.Value[0]
+	}
+	switch {
+	case status != nil && status.Err != nil:
+		entry.Status = JournalStatusFailed
+		entry.Error = fmt.Sprintf("%v", status.Err)
+	case status != nil && wm.confirmationReached(status.ConfirmationStatus):
+		entry.Status = JournalStatusConfirmed
+	case status != nil:
+		return entry, nil
+	default:
+		valid, err := wm.Client.IsBlockhashValid(wm.Context, tx.Message.RecentBlockhash, rpc.CommitmentProcessed)
+		if err != nil {
+			return entry, errors.Errorf("failed to check blockhash of %s. err: %s", entry.Signature, err.Error())
+		}
+		if valid.Value {
+			_, _ = wm.Client.SendTransactionWithOpts(wm.Context, tx, rpc.TransactionOpts{
+				SkipPreflight:       true,
+				PreflightCommitment: wm.Commitment,
+			})
+			return entry, nil
+		}
+		entry.Status = JournalStatusExpired
+	}
+	if err = wm.Journal.Put(entry); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+func (wm *WalletManager) journalEntryResult(entry JournalEntry) (*OperationResult, error) {
+	tx, err := decodeJournalTransaction(entry)
+	if err != nil {
+		return nil, err
+	}
+	return wm.fetchOperationResult(tx, tx.Signatures[0])
+}
+
+func (wm *WalletManager) confirmationReached(status rpc.ConfirmationStatusType) bool {
+	levels := map[rpc.ConfirmationStatusType]int{
+		rpc.ConfirmationStatusProcessed: 1,
+		rpc.ConfirmationStatusConfirmed: 2,
+		rpc.ConfirmationStatusFinalized: 3,
+	}
+	return levels[status] >= levels[wm.ConfirmationStatusType] && levels[status] > 0
+}
+
+func decodeJournalTransaction(entry JournalEntry) (*solana.Transaction, error) {
+	tx, err := decodeBase64Transaction(entry.Transaction)
+	if err != nil {
+		return nil, errors.Errorf("failed to decode journaled transaction %s. err: %s", entry.Key, err.Error())
+	}
+	if len(tx.Signatures) == 0 {
+		return nil, errors.Errorf("journaled transaction %s is not signed", entry.Key)
+	}
+	return tx, nil
+}
@@ -0,0 +1,151 @@
+package wallet_manager
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"os"
+	"solana-go-wm/internal/atomicfile"
+	"sort"
+	"sync"
+	"time"
+)
+
+type JournalStatus string
+
+const (
+	JournalStatusPending   JournalStatus = "pending"
+	JournalStatusConfirmed JournalStatus = "confirmed"
+	JournalStatusFailed    JournalStatus = "failed"
+	JournalStatusExpired   JournalStatus = "expired"
+)
+
+var (
+	ErrIdempotencyKeyFailed     = errors.New("transaction with this idempotency key failed on chain")
+	ErrIdempotencyKeyInProgress = errors.New("transaction with this idempotency key is already in progress")
+)
+
+type JournalEntry struct {
+	Key         string        `json:"key"`
+	Signature   string        `json:"signature"`
+	Transaction string        `json:"transaction"`
+	Status      JournalStatus `json:"status"`
+	Error       string        `json:"error,omitempty"`
+	Attempts    int           `json:"attempts"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+}
+
+// Journal is a write-ahead log of signed transactions stored in a local JSON file.
+// Every write replaces the file atomically, so a crash leaves either the previous
+// or the new state on disk.
+type Journal struct {
+	path     string
+	mu       sync.Mutex
+	entries  map[string]JournalEntry
+	reserved map[string]bool
+}
+
+func OpenJournal(path string) (*Journal, error) {
+	journal := &Journal{
+		path:     path,
+		entries:  map[string]JournalEntry{},
+		reserved: map[string]bool{},
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return journal, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("failed to read journal %s. err: %s", path, err.Error())
+	}
+	var entries []JournalEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.Errorf("failed to parse journal %s. err: %s", path, err.Error())
+	}
+	for _, entry := range entries {
+		journal.entries[entry.Key] = entry
+	}
+	return journal, nil
+}
+
+func (journal *Journal) Get(key string) (JournalEntry, bool) {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	entry, ok := journal.entries[key]
+	return entry, ok
+}
+
+// Reserve claims key until release is called, so two operations sharing an
+// idempotency key can not both sign and send a transaction.
+func (journal *Journal) Reserve(key string) (func(), error) {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	if journal.reserved[key] {
+		return nil, errors.Wrapf(ErrIdempotencyKeyInProgress, "key %s", key)
+	}
+	journal.reserved[key] = true
+	return func() {
+		journal.mu.Lock()
+		defer journal.mu.Unlock()
+		delete(journal.reserved, key)
+	}, nil
+}
+
+func (journal *Journal) Put(entry JournalEntry) error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	now := time.Now().UTC()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = now
+	}
+	entry.UpdatedAt = now
+	previous, existed := journal.entries[entry.Key]
+	journal.entries[entry.Key] = entry
+	if err := journal.flush(); err != nil {
+		if existed {
+			journal.entries[entry.Key] = previous
+		} else {
+			delete(journal.entries, entry.Key)
+		}
+		return err
+	}
+	return nil
+}
+
+func (journal *Journal) Entries() []JournalEntry {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	return journal.sortedEntries()
+}
+
+func (journal *Journal) Pending() []JournalEntry {
+	var pending []JournalEntry
+	for _, entry := range journal.Entries() {
+		if entry.Status == JournalStatusPending {
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+func (journal *Journal) sortedEntries() []JournalEntry {
+	var entries []JournalEntry
+	for _, entry := range journal.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+func (journal *Journal) flush() error {
+	data, err := json.MarshalIndent(journal.sortedEntries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(journal.path, data)
+}
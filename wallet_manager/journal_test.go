@@ -0,0 +1,148 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/pkg/errors"
+	"path/filepath"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+	"time"
+)
+
+func newJournaledFake(t *testing.T) (*fakerpc.Client, *WalletManager) {
+	fake := fakerpc.New()
+	fake.Handle("getSignatureStatuses", func(params []interface{}) (interface{}, error) {
+		if fake.Calls("sendTransaction") == 0 {
+			return fakerpc.Value([]interface{}{nil}), nil
+		}
+		return fakerpc.Value([]interface{}{
+			map[string]interface{}{"slot": 10, "err": nil, "confirmationStatus": "confirmed"},
+		}), nil
+	})
+	fake.Handle("isBlockhashValid", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value(false), nil
+	})
+	journal, err := OpenJournal(filepath.Join(t.TempDir(), "journal.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := newFakeWalletManager(fake)
+	manager.ConfirmationDelay = 10 * time.Millisecond
+	manager.Journal = journal
+	return fake, manager
+}
+
+func TestWalletManager_IdempotentSend(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	from := solana.NewWallet().PrivateKey
+	to := solana.NewWallet().PublicKey()
+
+	first, err := manager.WithIdempotencyKey("payout-1").SendLamports(from, to, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := manager.WithIdempotencyKey("payout-1").SendLamports(from, to, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.Calls("sendTransaction") != 1 {
+		t.Fatalf("expected a single send, got %d", fake.Calls("sendTransaction"))
+	}
+	if first.Replayed || !second.Replayed {
+		t.Fatal("only the repeated call should be marked as replayed")
+	}
+	if first.Signature() != second.Signature() {
+		t.Fatal("repeated key should return the recorded signature")
+	}
+	entry, ok := manager.Journal.Get("payout-1")
+	if !ok || entry.Status != JournalStatusConfirmed || entry.Attempts != 1 {
+		t.Fatalf("unexpected journal entry %+v", entry)
+	}
+}
+
+func TestWalletManager_ReconcileJournal(t *testing.T) {
+	_, manager := newJournaledFake(t)
+	from := solana.NewWallet().PrivateKey
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{makeTransferInstruction(from.PublicKey(), solana.NewWallet().PublicKey(), 1)},
+		solana.Hash{3},
+		solana.TransactionPayer(from.PublicKey()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = SignTransaction(tx, []solana.PrivateKey{from}); err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := tx.ToBase64()
+	err = manager.Journal.Put(JournalEntry{
+		Key:         "crashed",
+		Signature:   tx.Signatures[0].String(),
+		Transaction: encoded,
+		Status:      JournalStatusPending,
+		Attempts:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenJournal(manager.Journal.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.Journal = reopened
+	entries, err := manager.ReconcileJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Status != JournalStatusExpired {
+		t.Fatalf("expected the entry to expire, got %+v", entries)
+	}
+	if len(manager.Journal.Pending()) != 0 {
+		t.Fatal("no entries should stay pending")
+	}
+}
+
+func TestJournal_Reserve(t *testing.T) {
+	journal, err := OpenJournal(filepath.Join(t.TempDir(), "journal.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := journal.Reserve("payout-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = journal.Reserve("payout-1"); errors.Cause(err) != ErrIdempotencyKeyInProgress {
+		t.Fatalf("expected the reserved key to be refused, got %v", err)
+	}
+	if _, err = journal.Reserve("payout-2"); err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if _, err = journal.Reserve("payout-1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWalletManager_IdempotentSendRejected(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	fake.Handle("sendTransaction", func(params []interface{}) (interface{}, error) {
+		return nil, &jsonrpc.RPCError{Code: -32002, Message: "Transaction simulation failed"}
+	})
+	from := solana.NewWallet().PrivateKey
+
+	if _, err := manager.WithIdempotencyKey("payout-1").SendLamports(from, solana.NewWallet().PublicKey(), 1000); err == nil {
+		t.Fatal("expected the rejected send to fail")
+	}
+	entry, ok := manager.Journal.Get("payout-1")
+	if !ok || entry.Status != JournalStatusFailed {
+		t.Fatalf("expected the rejected entry to be failed, got %+v", entry)
+	}
+	if _, err := manager.ReconcileJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Calls("sendTransaction") != 1 {
+		t.Fatal("a rejected transaction must not be resubmitted")
+	}
+}
@@ -0,0 +1,52 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"unicode/utf8"
+)
+
+var (
+	MemoProgramID   = solana.MustPublicKeyFromBase58("MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr")
+	MemoV1ProgramID = solana.MustPublicKeyFromBase58("Memo1UhkJRfHyvLMcVucJwxXeuD728EqVDDwQDxFMNo")
+)
+
+// NewMemoInstruction builds an SPL Memo v2 instruction.
+// Every key in signers has to sign the transaction for the memo to be accepted.
+func NewMemoInstruction(memo string, signers ...solana.PublicKey) solana.Instruction {
+	var accounts solana.AccountMetaSlice
+	for _, signer := range signers {
+		accounts = append(accounts, solana.NewAccountMeta(signer, false, true))
+	}
+	return solana.NewInstruction(MemoProgramID, accounts, []byte(memo))
+}
+
+// AppendMemo appends a memo instruction to instructions unless memo is empty. With
+// signMemo set the memo has to be signed by signer.
+func AppendMemo(instructions []solana.Instruction, memo string, signMemo bool, signer solana.PublicKey) []solana.Instruction {
+	if memo == "" {
+		return instructions
+	}
+	if signMemo {
+		return append(instructions, NewMemoInstruction(memo, signer))
+	}
+	return append(instructions, NewMemoInstruction(memo))
+}
+
+// ParseMemos returns the text of every memo instruction in tx, in instruction order.
+func ParseMemos(tx *solana.Transaction) []string {
+	var memos []string
+	for _, instruction := range tx.Message.Instructions {
+		programID, err := tx.Message.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+		if err != nil {
+			continue
+		}
+		if !programID.Equals(MemoProgramID) && !programID.Equals(MemoV1ProgramID) {
+			continue
+		}
+		if !utf8.Valid(instruction.Data) {
+			continue
+		}
+		memos = append(memos, string(instruction.Data))
+	}
+	return memos
+}
@@ -0,0 +1,27 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"testing"
+)
+
+func TestParseMemos(t *testing.T) {
+	from := solana.NewWallet()
+	tx, err := solana.NewTransactionBuilder().
+		SetRecentBlockHash(solana.Hash{}).
+		SetFeePayer(from.PublicKey()).
+		AddInstruction(makeTransferInstruction(from.PublicKey(), solana.NewWallet().PublicKey(), 1)).
+		AddInstruction(NewMemoInstruction("payout #1", from.PublicKey())).
+		AddInstruction(NewMemoInstruction("payout #2")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	memos := ParseMemos(tx)
+	if len(memos) != 2 || memos[0] != "payout #1" || memos[1] != "payout #2" {
+		t.Fatalf("unexpected memos %v", memos)
+	}
+	if tx.Message.Header.NumRequiredSignatures != 1 {
+		t.Fatalf("memo signer should be the fee payer, got %d signers", tx.Message.Header.NumRequiredSignatures)
+	}
+}
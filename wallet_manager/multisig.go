@@ -0,0 +1,163 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/pkg/errors"
+)
+
+const TokenMultisigSize = 355
+
+type SendMultisigTokensInstructionParams struct {
+	Multisig        solana.PublicKey
+	MultisigSigners []solana.PublicKey
+	To              solana.PublicKey
+	Mint            solana.PublicKey
+	Amount          uint64
+	Memo            string
+}
+
+func (wm *WalletManager) CreateTokenMultisig(
+	payer solana.PrivateKey,
+	m uint8,
+	signers []solana.PublicKey,
+) (solana.PublicKey, *OperationResult, error) {
+	if len(signers) == 0 || len(signers) > token.MAX_SIGNERS {
+		return solana.PublicKey{}, nil, errors.Errorf(
+			"multisig needs from 1 to %d signers, got %d",
+			token.MAX_SIGNERS,
+			len(signers),
+		)
+	}
+	if m == 0 || int(m) > len(signers) {
+		return solana.PublicKey{}, nil, errors.Errorf("invalid threshold %d of %d", m, len(signers))
+	}
+	multisig := solana.NewWallet().PrivateKey
+	lamports, err := wm.Client.GetMinimumBalanceForRentExemption(wm.Context, TokenMultisigSize, wm.Commitment)
+	if err != nil {
+		return solana.PublicKey{}, nil, errors.Errorf("failed to get rent exemption. err: %s", err.Error())
+	}
+	createInstruction := system.NewCreateAccountInstructionBuilder().
+		SetLamports(lamports).
+		SetSpace(TokenMultisigSize).
+		SetOwner(solana.TokenProgramID).
+		SetFundingAccount(payer.PublicKey()).
+		SetNewAccount(multisig.PublicKey()).
+		Build()
+	initInstruction := token.NewInitializeMultisigInstructionBuilder().
+		SetM(m).
+		SetAccount(multisig.PublicKey()).
+		SetSysVarRentPubkeyAccount(solana.SysVarRentPubkey).
+		AddSigners(signers...).
+		Build()
+	result, err := wm.SendAndConfirmInstructions(
+		payer.PublicKey(),
+		[]solana.Instruction{createInstruction, initInstruction},
+		[]solana.PrivateKey{payer, multisig},
+	)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	return multisig.PublicKey(), result, nil
+}
+
+func (wm *WalletManager) GetTokenMultisig(multisig solana.PublicKey) (token.Multisig, error) {
+	var data token.Multisig
+	err := wm.Client.GetAccountDataInto(wm.Context, multisig, &data)
+	if err != nil {
+		return token.Multisig{}, errors.Errorf("failed to get multisig %s. err: %s", multisig.String(), err.Error())
+	}
+	return data, nil
+}
+
+func (wm *WalletManager) SendMultisigTokensTransaction(
+	feePayer solana.PrivateKey,
+	instructionsParams []SendMultisigTokensInstructionParams,
+	signers []solana.PrivateKey,
+) (*OperationResult, error) {
+	ptx, err := wm.PrepareMultisigTokensTransaction(
+		feePayer.PublicKey(),
+		instructionsParams,
+		appendSignerIfNotPresented(signers, feePayer),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer ptx.Discard()
+	return wm.SubmitPartialTransaction(ptx)
+}
+
+// PrepareMultisigTokensTransaction builds a transfer from multisig-owned token accounts
+// and signs it with the available signers only. Missing signatures are left empty
+// for co-signers to fill in with PartialTransaction.Sign.
+func (wm *WalletManager) PrepareMultisigTokensTransaction(
+	feePayer solana.PublicKey,
+	instructionsParams []SendMultisigTokensInstructionParams,
+	signers []solana.PrivateKey,
+) (*PartialTransaction, error) {
+	var instructions []solana.Instruction
+	for _, params := range instructionsParams {
+		multisig, err := wm.GetTokenMultisig(params.Multisig)
+		if err != nil {
+			return nil, err
+		}
+		if len(params.MultisigSigners) < int(multisig.M) {
+			return nil, errors.Errorf(
+				"multisig %s requires %d signers, %d supplied",
+				params.Multisig.String(),
+				multisig.M,
+				len(params.MultisigSigners),
+			)
+		}
+		seen := map[solana.PublicKey]bool{}
+		for _, signer := range params.MultisigSigners {
+			if !isMultisigSigner(multisig, signer) {
+				return nil, errors.Errorf("%s is not a signer of multisig %s", signer.String(), params.Multisig.String())
+			}
+			if seen[signer] {
+				return nil, errors.Errorf("%s is listed twice as signer of multisig %s", signer.String(), params.Multisig.String())
+			}
+			seen[signer] = true
+		}
+		fromAssociatedAddress, _, err := solana.FindAssociatedTokenAddress(params.Multisig, params.Mint)
+		if err != nil {
+			return nil, err
+		}
+		toAssociatedAddress, createInstruction, err := wm.getOrCreateAssociatedTokenAddress(feePayer, params.To, params.Mint)
+		if err != nil {
+			return nil, errors.Errorf(
+				"failed to find associated token address for %s. err: %s",
+				params.To.String(),
+				err.Error(),
+			)
+		}
+		if createInstruction != nil {
+			instructions = append(instructions, createInstruction)
+		}
+		instruction := token.NewTransferInstructionBuilder().
+			SetAmount(params.Amount).
+			SetSourceAccount(fromAssociatedAddress).
+			SetDestinationAccount(toAssociatedAddress).
+			SetOwnerAccount(params.Multisig, params.MultisigSigners...).
+			Build()
+		instructions = append(instructions, instruction)
+		if params.Memo != "" {
+			instructions = append(instructions, NewMemoInstruction(params.Memo))
+		}
+	}
+	tx, err := wm.BuildTransaction(feePayer, instructions)
+	if err != nil {
+		return nil, err
+	}
+	return wm.preparePartialTransaction(tx, signers)
+}
+
+func isMultisigSigner(multisig token.Multisig, key solana.PublicKey) bool {
+	for _, signer := range multisig.Signers[:multisig.N] {
+		if signer.Equals(key) {
+			return true
+		}
+	}
+	return false
+}
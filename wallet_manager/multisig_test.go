@@ -0,0 +1,46 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+)
+
+func TestWalletManager_SendMultisigTokensTransaction(t *testing.T) {
+	fake := fakerpc.New()
+	manager := newFakeWalletManager(fake)
+	feePayer := solana.NewWallet().PrivateKey
+	first := solana.NewWallet().PrivateKey
+	second := solana.NewWallet().PrivateKey
+	multisig := solana.NewWallet().PublicKey()
+	data := make([]byte, TokenMultisigSize)
+	data[0], data[1], data[2] = 2, 3, 1
+	copy(data[3:35], first.PublicKey().Bytes())
+	copy(data[35:67], second.PublicKey().Bytes())
+	copy(data[67:99], solana.NewWallet().PublicKey().Bytes())
+	fake.SetAccount(multisig, solana.TokenProgramID, 1, data)
+	params := SendMultisigTokensInstructionParams{
+		Multisig:        multisig,
+		MultisigSigners: []solana.PublicKey{first.PublicKey(), first.PublicKey()},
+		To:              solana.NewWallet().PublicKey(),
+		Mint:            solana.NewWallet().PublicKey(),
+		Amount:          10,
+	}
+
+	if _, err := manager.SendMultisigTokensTransaction(feePayer, []SendMultisigTokensInstructionParams{params}, []solana.PrivateKey{first}); err == nil {
+		t.Fatal("expected a duplicate multisig signer to be rejected")
+	}
+	params.MultisigSigners = []solana.PublicKey{first.PublicKey(), second.PublicKey()}
+	if _, err := manager.SendMultisigTokensTransaction(feePayer, []SendMultisigTokensInstructionParams{params}, []solana.PrivateKey{first}); err == nil {
+		t.Fatal("expected a transaction signed by 1 of 2 multisig signers to be rejected")
+	}
+	if fake.Calls("sendTransaction") != 0 {
+		t.Fatal("an incompletely signed transaction must not be sent")
+	}
+	if _, err := manager.SendMultisigTokensTransaction(feePayer, []SendMultisigTokensInstructionParams{params}, []solana.PrivateKey{first, second}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Calls("sendTransaction") != 1 {
+		t.Fatal("expected the fully signed transaction to be sent")
+	}
+}
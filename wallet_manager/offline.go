@@ -0,0 +1,107 @@
+package wallet_manager
+
+import (
+	"encoding/json"
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+)
+
+const ExportedTransactionVersion = 1
+
+// ExportedTransaction is the envelope used to move a transaction between an online
+// machine and an offline signer. Transaction holds the base64 wire format; Summary
+// is informational only and is rebuilt from the transaction on import.
+type ExportedTransaction struct {
+	Version         int      `json:"version"`
+	Transaction     string   `json:"transaction"`
+	RequiredSigners []string `json:"requiredSigners"`
+	MissingSigners  []string `json:"missingSigners"`
+	Summary         string   `json:"summary"`
+}
+
+// ExportTransaction encodes a copy of tx, so the caller's transaction is left untouched.
+func ExportTransaction(tx *solana.Transaction) ([]byte, error) {
+	tx, err := copyTransaction(tx)
+	if err != nil {
+		return nil, errors.Errorf("failed to copy transaction. err: %s", err.Error())
+	}
+	if len(tx.Signatures) == 0 {
+		tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	}
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		return nil, errors.Errorf("failed to encode transaction. err: %s", err.Error())
+	}
+	exported := ExportedTransaction{
+		Version:     ExportedTransactionVersion,
+		Transaction: encoded,
+		Summary:     SummarizeTransaction(tx),
+	}
+	for _, signer := range tx.Message.Signers() {
+		exported.RequiredSigners = append(exported.RequiredSigners, signer.String())
+	}
+	for _, signer := range MissingSigners(tx) {
+		exported.MissingSigners = append(exported.MissingSigners, signer.String())
+	}
+	return json.MarshalIndent(exported, "", "  ")
+}
+
+func ImportTransaction(data []byte) (*solana.Transaction, error) {
+	var exported ExportedTransaction
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, errors.Errorf("failed to parse exported transaction. err: %s", err.Error())
+	}
+	if exported.Version != ExportedTransactionVersion {
+		return nil, errors.Errorf("unsupported exported transaction version %d", exported.Version)
+	}
+	tx, err := decodeBase64Transaction(exported.Transaction)
+	if err != nil {
+		return nil, errors.Errorf("failed to decode transaction. err: %s", err.Error())
+	}
+	if err = verifyPresentSignatures(tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// SignExportedTransaction is meant to run on the offline machine: it imports data,
+// adds signatures of signers and exports the result again.
+func SignExportedTransaction(data []byte, signers []solana.PrivateKey) ([]byte, error) {
+	tx, err := ImportTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, signer := range signers {
+		if !tx.IsSigner(signer.PublicKey()) {
+			return nil, errors.Errorf("%s is not a required signer of the transaction", signer.PublicKey().String())
+		}
+	}
+	if err = PartialSignTransaction(tx, signers); err != nil {
+		return nil, err
+	}
+	return ExportTransaction(tx)
+}
+
+func (wm *WalletManager) SubmitExportedTransaction(data []byte) (*OperationResult, error) {
+	ptx, err := UnmarshalPartialTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	return wm.SubmitPartialTransaction(ptx)
+}
+
+func verifyPresentSignatures(tx *solana.Transaction) error {
+	payload, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	for idx, signer := range tx.Message.Signers() {
+		if idx >= len(tx.Signatures) || tx.Signatures[idx].IsZero() {
+			continue
+		}
+		if !tx.Signatures[idx].Verify(signer, payload) {
+			return errors.Errorf("invalid signature of %s", signer.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"strings"
+	"testing"
+)
+
+func TestExportedTransaction_SignOffline(t *testing.T) {
+	from := solana.NewWallet().PrivateKey
+	to := solana.NewWallet().PublicKey()
+	tx, err := solana.NewTransactionBuilder().
+		SetRecentBlockHash(solana.Hash{1}).
+		SetFeePayer(from.PublicKey()).
+		AddInstruction(makeTransferInstruction(from.PublicKey(), to, 1500)).
+		AddInstruction(NewMemoInstruction("invoice 42")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ExportTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(exported), to.String()) || !strings.Contains(string(exported), "invoice 42") {
+		t.Fatalf("summary does not describe the transfer: %s", exported)
+	}
+	if len(tx.Signatures) != 0 {
+		t.Fatal("export must not modify the exported transaction")
+	}
+	if _, err = SignExportedTransaction(exported, []solana.PrivateKey{from, solana.NewWallet().PrivateKey}); err == nil {
+		t.Fatal("expected a key which is not a required signer to be rejected")
+	}
+
+	signed, err := SignExportedTransaction(exported, []solana.PrivateKey{from})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ImportTransaction(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing := MissingSigners(imported); len(missing) != 0 {
+		t.Fatalf("unexpected missing signers %v", missing)
+	}
+	if err = imported.VerifySignatures(); err != nil {
+		t.Fatal(err)
+	}
+
+	imported.Message.Instructions[0].Data[4]++
+	tampered, err := ExportTransaction(imported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ImportTransaction(tampered); err == nil {
+		t.Fatal("import should reject a transaction whose signature does not match the message")
+	}
+}
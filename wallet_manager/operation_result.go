@@ -0,0 +1,179 @@
+package wallet_manager
+
+import (
+	"fmt"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+type OperationResult struct {
+	Signatures      []solana.Signature
+	Slot            uint64
+	BlockTime       *solana.UnixTimeSeconds
+	Fee             uint64
+	UnitsConsumed   uint64
+	LamportsChanges []LamportsChange
+	TokenChanges    []TokenBalanceChange
+	Logs            []string
+	Simulated       bool
+	Replayed        bool
+	// DetailsMissing is set when the transaction landed but its meta could not be
+	// loaded. Only Signatures is filled in then.
+	DetailsMissing bool
+}
+
+func (result *OperationResult) Signature() solana.Signature {
+	if result == nil || len(result.Signatures) == 0 {
+		return solana.Signature{}
+	}
+	return result.Signatures[0]
+}
+
+func (result *OperationResult) LamportsDelta(account solana.PublicKey) int64 {
+	for _, change := range result.LamportsChanges {
+		if change.Account.Equals(account) {
+			return change.Delta()
+		}
+	}
+	return 0
+}
+
+func (result *OperationResult) TokenDelta(owner, mint solana.PublicKey) int64 {
+	var delta int64
+	for _, change := range result.TokenChanges {
+		if change.Owner.Equals(owner) && change.Mint.Equals(mint) {
+			delta += change.Delta()
+		}
+	}
+	return delta
+}
+
+// UnconfirmedError is returned when a transaction was handed to the cluster but its
+// confirmation could not be observed. The transaction may still land.
+type UnconfirmedError struct {
+	Signature solana.Signature
+	Err       error
+}
+
+func (e *UnconfirmedError) Error() string {
+	return fmt.Sprintf("transaction %s is not confirmed: %s", e.Signature.String(), e.Err.Error())
+}
+
+func IsUnconfirmed(err error) bool {
+	_, ok := errors.Cause(err).(*UnconfirmedError)
+	return ok
+}
+
+// fetchOperationResult loads the meta of a confirmed transaction. The transaction
+// has already landed at this point, so a failed lookup is not an error: the result
+// only holds the signature and is marked with DetailsMissing.
+func (wm *WalletManager) fetchOperationResult(tx *solana.Transaction, signature solana.Signature) (*OperationResult, error) {
+	result := &OperationResult{Signatures: []solana.Signature{signature}}
+	txResult, err := wm.Client.GetTransaction(wm.Context, signature, &rpc.GetTransactionOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: wm.Commitment,
+	})
+	if err != nil || txResult.Meta == nil {
+		result.DetailsMissing = true
+		return result, nil
+	}
+	result.Slot = txResult.Slot
+	result.BlockTime = txResult.BlockTime
+	result.Fee = txResult.Meta.Fee
+	result.Logs = txResult.Meta.LogMessages
+	result.UnitsConsumed = parseUnitsConsumed(txResult.Meta.LogMessages)
+	keys := tx.Message.AccountKeys
+	for idx, key := range keys {
+		if idx >= len(txResult.Meta.PreBalances) || idx >= len(txResult.Meta.PostBalances) {
+			break
+		}
+		result.LamportsChanges = append(result.LamportsChanges, LamportsChange{
+			Account: key,
+			Pre:     txResult.Meta.PreBalances[idx],
+			Post:    txResult.Meta.PostBalances[idx],
+		})
+	}
+	result.TokenChanges = diffTokenBalances(keys, txResult.Meta.PreTokenBalances, txResult.Meta.PostTokenBalances)
+	return result, nil
+}
+
+func simulationOperationResult(simulation *SimulationResult) *OperationResult {
+	return &OperationResult{
+		Signatures:      []solana.Signature{simulation.Signature},
+		Fee:             simulation.Fee,
+		UnitsConsumed:   simulation.UnitsConsumed,
+		LamportsChanges: simulation.LamportsChanges,
+		TokenChanges:    simulation.TokenChanges,
+		Logs:            simulation.Logs,
+		Simulated:       true,
+	}
+}
+
+func diffTokenBalances(keys []solana.PublicKey, pre, post []rpc.TokenBalance) []TokenBalanceChange {
+	changes := map[uint16]*TokenBalanceChange{}
+	var order []uint16
+	apply := func(balances []rpc.TokenBalance, isPost bool) {
+		for _, balance := range balances {
+			if int(balance.AccountIndex) >= len(keys) {
+				continue
+			}
+			change, ok := changes[balance.AccountIndex]
+			if !ok {
+				change = &TokenBalanceChange{Account: keys[balance.AccountIndex]}
+				changes[balance.AccountIndex] = change
+				order = append(order, balance.AccountIndex)
+			}
+			change.Mint = balance.Mint
+			if balance.Owner != nil {
+				change.Owner = *balance.Owner
+			}
+			var amount uint64
+			if balance.UiTokenAmount != nil {
+				amount, _ = strconv.ParseUint(balance.UiTokenAmount.Amount, 10, 64)
+			}
+			if isPost {
+				change.Post = amount
+			} else {
+				change.Pre = amount
+			}
+		}
+	}
+	apply(pre, false)
+	apply(post, true)
+	var result []TokenBalanceChange
+	for _, idx := range order {
+		result = append(result, *changes[idx])
+	}
+	return result
+}
+
+// parseUnitsConsumed sums compute units reported by top-level program invocations.
+func parseUnitsConsumed(logs []string) uint64 {
+	var total uint64
+	depth := 0
+	for _, line := range logs {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "Program" {
+			continue
+		}
+		switch fields[2] {
+		case "invoke":
+			if len(fields) > 3 {
+				fmt.Sscanf(fields[3], "[%d]", &depth)
+			}
+		case "consumed":
+			if depth == 1 && len(fields) > 3 {
+				units, err := strconv.ParseUint(fields[3], 10, 64)
+				if err == nil {
+					total += units
+				}
+			}
+		case "success", "failed:":
+			depth--
+		}
+	}
+	return total
+}
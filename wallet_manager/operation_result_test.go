@@ -0,0 +1,88 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+	"time"
+)
+
+func TestWalletManager_SendLamportsResult(t *testing.T) {
+	from := solana.NewWallet().PrivateKey
+	to := solana.NewWallet().PublicKey()
+	fake := fakerpc.New()
+	var sent string
+	fake.Handle("sendTransaction", func(params []interface{}) (interface{}, error) {
+		sent = params[0].(string)
+		return solana.Signature{9}.String(), nil
+	})
+	fake.Handle("getSignatureStatuses", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value([]interface{}{
+			map[string]interface{}{"slot": 77, "err": nil, "confirmationStatus": "confirmed"},
+		}), nil
+	})
+	fake.Handle("getTransaction", func(params []interface{}) (interface{}, error) {
+		return map[string]interface{}{
+			"slot":        77,
+			"blockTime":   1666000000,
+			"transaction": []string{sent, "base64"},
+			"meta": map[string]interface{}{
+				"err":          nil,
+				"fee":          5000,
+				"preBalances":  []uint64{2000000, 0, 1},
+				"postBalances": []uint64{995000, 1000000, 1},
+				"logMessages": []string{
+					"Program 11111111111111111111111111111111 invoke [1]",
+					"Program 11111111111111111111111111111111 consumed 150 of 200000 compute units",
+					"Program 11111111111111111111111111111111 success",
+				},
+			},
+		}, nil
+	})
+	manager := newFakeWalletManager(fake)
+	manager.ConfirmationDelay = 10 * time.Millisecond
+
+	result, err := manager.SendLamports(from, to, 1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Signature() != (solana.Signature{9}) || result.Slot != 77 || result.Fee != 5000 || result.UnitsConsumed != 150 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.LamportsDelta(from.PublicKey()) != -1005000 || result.LamportsDelta(to) != 1000000 {
+		t.Fatalf("unexpected balance changes %+v", result.LamportsChanges)
+	}
+}
+
+func TestWalletManager_SendLamportsResultLookupError(t *testing.T) {
+	fake := fakerpc.New()
+	fake.Handle("getTransaction", func(params []interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	manager := newFakeWalletManager(fake)
+
+	result, err := manager.SendLamports(solana.NewWallet().PrivateKey, solana.NewWallet().PublicKey(), 1000)
+	if err != nil {
+		t.Fatalf("a landed transaction must not fail on the lookup of its details. err: %s", err.Error())
+	}
+	if result == nil || !result.DetailsMissing || result.Signature() != fake.LastSent().Signatures[0] {
+		t.Fatalf("expected the signature of the landed transaction without details, got %+v", result)
+	}
+}
+
+func TestParseUnitsConsumed(t *testing.T) {
+	logs := []string{
+		"Program hausS13jsjafwWwGqZTUQRmWyvyxn9EQpqMwV1PBBmk invoke [1]",
+		"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA invoke [2]",
+		"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA consumed 2000 of 180000 compute units",
+		"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA success",
+		"Program hausS13jsjafwWwGqZTUQRmWyvyxn9EQpqMwV1PBBmk consumed 30000 of 200000 compute units",
+		"Program hausS13jsjafwWwGqZTUQRmWyvyxn9EQpqMwV1PBBmk success",
+		"Program MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr invoke [1]",
+		"Program MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr consumed 500 of 170000 compute units",
+		"Program MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr success",
+	}
+	if units := parseUnitsConsumed(logs); units != 30500 {
+		t.Fatalf("expected 30500 units, got %d", units)
+	}
+}
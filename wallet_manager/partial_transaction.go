@@ -0,0 +1,133 @@
+package wallet_manager
+
+import (
+	"bytes"
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+)
+
+// PartialTransaction is a transaction passed between parties, each adding its own
+// signature, until every required signature is present.
+type PartialTransaction struct {
+	Transaction *solana.Transaction
+
+	reservation *policyReservation
+}
+
+func NewPartialTransaction(tx *solana.Transaction) *PartialTransaction {
+	if len(tx.Signatures) == 0 {
+		tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	}
+	return &PartialTransaction{Transaction: tx}
+}
+
+func (wm *WalletManager) PrepareInstructions(
+	feePayer solana.PublicKey,
+	instructions []solana.Instruction,
+	signers []solana.PrivateKey,
+) (*PartialTransaction, error) {
+	tx, err := wm.BuildTransaction(feePayer, instructions)
+	if err != nil {
+		return nil, err
+	}
+	return wm.preparePartialTransaction(tx, signers)
+}
+
+// preparePartialTransaction reserves the spending of tx and signs it with signers.
+// The reservation stays attached to the returned transaction until it is submitted
+// or discarded.
+func (wm *WalletManager) preparePartialTransaction(tx *solana.Transaction, signers []solana.PrivateKey) (*PartialTransaction, error) {
+	reserved, err := wm.reservePolicy(tx)
+	if err != nil {
+		return nil, err
+	}
+	ptx := NewPartialTransaction(tx)
+	ptx.reservation = reserved.policyReservation
+	if err = PartialSignTransaction(tx, signers); err != nil {
+		ptx.Discard()
+		return nil, err
+	}
+	return ptx, nil
+}
+
+func UnmarshalPartialTransaction(data []byte) (*PartialTransaction, error) {
+	tx, err := ImportTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewPartialTransaction(tx), nil
+}
+
+func (ptx *PartialTransaction) Marshal() ([]byte, error) {
+	return ExportTransaction(ptx.Transaction)
+}
+
+func (ptx *PartialTransaction) Summary() string {
+	return SummarizeTransaction(ptx.Transaction)
+}
+
+// Matches reports whether the transaction consists exactly of instructions paid by
+// feePayer. Parties use it to check what they sign before calling Sign.
+func (ptx *PartialTransaction) Matches(feePayer solana.PublicKey, instructions []solana.Instruction) error {
+	expected, err := solana.NewTransaction(
+		instructions,
+		ptx.Transaction.Message.RecentBlockhash,
+		solana.TransactionPayer(feePayer),
+	)
+	if err != nil {
+		return err
+	}
+	expectedMessage, err := expected.Message.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	actualMessage, err := ptx.Transaction.Message.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(expectedMessage, actualMessage) {
+		return errors.New("transaction does not match the expected instructions")
+	}
+	return nil
+}
+
+func (ptx *PartialTransaction) Sign(signers ...solana.PrivateKey) error {
+	for _, signer := range signers {
+		if !ptx.Transaction.IsSigner(signer.PublicKey()) {
+			return errors.Errorf("%s is not a signer of the transaction", signer.PublicKey().String())
+		}
+	}
+	return PartialSignTransaction(ptx.Transaction, signers)
+}
+
+// Discard releases the spending reserved when the transaction was prepared unless
+// it has been sent. Call it when the transaction is abandoned or its blockhash expired.
+func (ptx *PartialTransaction) Discard() {
+	ptx.reservation.finish()
+	ptx.reservation = nil
+}
+
+func (ptx *PartialTransaction) MissingSigners() []solana.PublicKey {
+	return MissingSigners(ptx.Transaction)
+}
+
+func (ptx *PartialTransaction) IsFullySigned() bool {
+	return len(ptx.MissingSigners()) == 0
+}
+
+func (wm *WalletManager) SubmitPartialTransaction(ptx *PartialTransaction) (*OperationResult, error) {
+	if missing := ptx.MissingSigners(); len(missing) > 0 {
+		return nil, errors.Errorf("transaction is missing %d signatures, first: %s", len(missing), missing[0].String())
+	}
+	if err := ptx.Transaction.VerifySignatures(); err != nil {
+		return nil, err
+	}
+	if ptx.reservation == nil {
+		return wm.SendAndConfirmTransaction(ptx.Transaction)
+	}
+	// spend the reservation taken when the transaction was prepared
+	reserved := *wm
+	reserved.policyReservation = ptx.reservation
+	defer ptx.Discard()
+	return reserved.SendAndConfirmTransaction(ptx.Transaction)
+}
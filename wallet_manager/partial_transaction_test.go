@@ -0,0 +1,53 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"testing"
+)
+
+func TestPartialTransaction_TwoParties(t *testing.T) {
+	buyer := solana.NewWallet().PrivateKey
+	authority := solana.NewWallet().PrivateKey
+	instructions := []solana.Instruction{
+		makeTransferInstruction(buyer.PublicKey(), authority.PublicKey(), 1000),
+		NewMemoInstruction("approved", authority.PublicKey()),
+	}
+	tx, err := solana.NewTransaction(instructions, solana.Hash{7}, solana.TransactionPayer(buyer.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ptx := NewPartialTransaction(tx)
+	if err = ptx.Sign(buyer); err != nil {
+		t.Fatal(err)
+	}
+	if missing := ptx.MissingSigners(); len(missing) != 1 || !missing[0].Equals(authority.PublicKey()) {
+		t.Fatalf("expected authority to be missing, got %v", missing)
+	}
+	data, err := ptx.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received, err := UnmarshalPartialTransaction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = received.Matches(buyer.PublicKey(), instructions); err != nil {
+		t.Fatal(err)
+	}
+	if err = received.Matches(buyer.PublicKey(), instructions[:1]); err == nil {
+		t.Fatal("expected mismatch for different instructions")
+	}
+	if err = received.Sign(solana.NewWallet().PrivateKey); err == nil {
+		t.Fatal("expected error for a key that is not a signer")
+	}
+	if err = received.Sign(authority); err != nil {
+		t.Fatal(err)
+	}
+	if !received.IsFullySigned() {
+		t.Fatal("transaction should be fully signed")
+	}
+	if err = received.Transaction.VerifySignatures(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,288 @@
+package wallet_manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"os"
+	"solana-go-wm/internal/atomicfile"
+	"sync"
+	"time"
+)
+
+type PolicyRule string
+
+const (
+	PolicyRuleDailyLamports       PolicyRule = "daily_lamports"
+	PolicyRuleWalletDailyLamports PolicyRule = "wallet_daily_lamports"
+	PolicyRuleDailyMint           PolicyRule = "daily_mint"
+	PolicyRuleWalletDailyMint     PolicyRule = "wallet_daily_mint"
+	PolicyRuleDeniedRecipient     PolicyRule = "denied_recipient"
+	PolicyRuleRecipientNotAllowed PolicyRule = "recipient_not_allowed"
+)
+
+type PolicyViolationError struct {
+	Rule      PolicyRule
+	Action    PolicyAction
+	Limit     uint64
+	Requested uint64
+}
+
+func (e *PolicyViolationError) Error() string {
+	switch e.Rule {
+	case PolicyRuleDeniedRecipient, PolicyRuleRecipientNotAllowed:
+		return fmt.Sprintf("policy %s: recipient %s", e.Rule, e.Action.Recipient.String())
+	}
+	return fmt.Sprintf(
+		"policy %s: wallet %s requested %d, limit is %d",
+		e.Rule,
+		e.Action.Wallet.String(),
+		e.Requested,
+		e.Limit,
+	)
+}
+
+func IsPolicyViolation(err error) bool {
+	_, ok := errors.Cause(err).(*PolicyViolationError)
+	return ok
+}
+
+// SpendingPolicy limits outgoing value. Zero limits mean unlimited, keys of the
+// maps are base58 wallet and mint addresses.
+type SpendingPolicy struct {
+	DailyLamportsLimit              uint64                       `json:"dailyLamportsLimit"`
+	DefaultWalletDailyLamportsLimit uint64                       `json:"defaultWalletDailyLamportsLimit"`
+	WalletDailyLamportsLimits       map[string]uint64            `json:"walletDailyLamportsLimits"`
+	DailyMintLimits                 map[string]uint64            `json:"dailyMintLimits"`
+	WalletDailyMintLimits           map[string]map[string]uint64 `json:"walletDailyMintLimits"`
+	AllowedRecipients               []string                     `json:"allowedRecipients"`
+	DeniedRecipients                []string                     `json:"deniedRecipients"`
+	// MaxAuctionHouseBuyPrice is enforced by auction house actors built on a
+	// manager using this policy.
+	MaxAuctionHouseBuyPrice uint64 `json:"maxAuctionHouseBuyPrice"`
+}
+
+// PolicyCheck is an additional rule run for every action before spending is
+// reserved. Packages decoding their own programs register checks for their actions.
+type PolicyCheck func(action PolicyAction) error
+
+func LoadSpendingPolicy(path string) (SpendingPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SpendingPolicy{}, errors.Errorf("failed to read policy %s. err: %s", path, err.Error())
+	}
+	var policy SpendingPolicy
+	if err = json.Unmarshal(data, &policy); err != nil {
+		return SpendingPolicy{}, errors.Errorf("failed to parse policy %s. err: %s", path, err.Error())
+	}
+	return policy, nil
+}
+
+// PolicyEngine enforces a SpendingPolicy and keeps track of what has been spent
+// during the current UTC day. Spending is reserved before signing and released
+// again when the transaction is not sent. Engines opened with a path keep the
+// spending in a local JSON file, so restarts do not reset the daily limits.
+type PolicyEngine struct {
+	Policy SpendingPolicy
+	Checks []PolicyCheck
+	Now    func() time.Time
+
+	path       string
+	mu         sync.Mutex
+	spending   policySpending
+	checkNames map[string]int
+}
+
+type policySpending struct {
+	Day            string                                           `json:"day"`
+	TotalLamports  uint64                                           `json:"totalLamports"`
+	WalletLamports map[solana.PublicKey]uint64                      `json:"walletLamports"`
+	MintTotals     map[solana.PublicKey]uint64                      `json:"mintTotals"`
+	WalletMints    map[solana.PublicKey]map[solana.PublicKey]uint64 `json:"walletMints"`
+}
+
+func NewPolicyEngine(policy SpendingPolicy) *PolicyEngine {
+	return &PolicyEngine{
+		Policy: policy,
+		Now:    time.Now,
+	}
+}
+
+func OpenPolicyEngine(path string, policy SpendingPolicy) (*PolicyEngine, error) {
+	engine := NewPolicyEngine(policy)
+	engine.path = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return engine, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("failed to read policy spending %s. err: %s", path, err.Error())
+	}
+	if err = json.Unmarshal(data, &engine.spending); err != nil {
+		return nil, errors.Errorf("failed to parse policy spending %s. err: %s", path, err.Error())
+	}
+	return engine, nil
+}
+
+// AddCheck registers an additional rule checked for every action.
+func (engine *PolicyEngine) AddCheck(check PolicyCheck) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.Checks = append(engine.Checks, check)
+}
+
+// SetCheck registers check under name, replacing the check registered under the
+// same name before. Code wiring checks for every actor it builds uses it to add
+// each rule once.
+func (engine *PolicyEngine) SetCheck(name string, check PolicyCheck) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	if idx, ok := engine.checkNames[name]; ok {
+		engine.Checks[idx] = check
+		return
+	}
+	if engine.checkNames == nil {
+		engine.checkNames = map[string]int{}
+	}
+	engine.checkNames[name] = len(engine.Checks)
+	engine.Checks = append(engine.Checks, check)
+}
+
+func (engine *PolicyEngine) Reserve(actions []PolicyAction) (release func(), err error) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.rollDay()
+	for _, action := range actions {
+		if err = engine.checkRecipient(action); err != nil {
+			return nil, err
+		}
+		for _, check := range engine.Checks {
+			if err = check(action); err != nil {
+				return nil, err
+			}
+		}
+	}
+	day := engine.spending.Day
+	var applied []PolicyAction
+	for _, action := range actions {
+		if err = engine.apply(action); err != nil {
+			engine.revertAll(applied)
+			return nil, err
+		}
+		applied = append(applied, action)
+	}
+	if err = engine.flush(); err != nil {
+		engine.revertAll(applied)
+		return nil, err
+	}
+	return func() {
+		engine.mu.Lock()
+		defer engine.mu.Unlock()
+		if engine.spending.Day != day {
+			return
+		}
+		engine.revertAll(applied)
+		// a failed write keeps the higher spending on disk, which errs on the safe side
+		_ = engine.flush()
+	}, nil
+}
+
+func (engine *PolicyEngine) SpentLamports(wallet solana.PublicKey) uint64 {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.rollDay()
+	return engine.spending.WalletLamports[wallet]
+}
+
+func (engine *PolicyEngine) rollDay() {
+	day := engine.Now().UTC().Format("2006-01-02")
+	if engine.spending.Day == day {
+		return
+	}
+	engine.spending = policySpending{
+		Day:            day,
+		WalletLamports: map[solana.PublicKey]uint64{},
+		MintTotals:     map[solana.PublicKey]uint64{},
+		WalletMints:    map[solana.PublicKey]map[solana.PublicKey]uint64{},
+	}
+}
+
+func (engine *PolicyEngine) checkRecipient(action PolicyAction) error {
+	if action.Recipient.IsZero() {
+		return nil
+	}
+	recipient := action.Recipient.String()
+	for _, denied := range engine.Policy.DeniedRecipients {
+		if denied == recipient {
+			return &PolicyViolationError{Rule: PolicyRuleDeniedRecipient, Action: action}
+		}
+	}
+	if len(engine.Policy.AllowedRecipients) == 0 {
+		return nil
+	}
+	for _, allowed := range engine.Policy.AllowedRecipients {
+		if allowed == recipient {
+			return nil
+		}
+	}
+	return &PolicyViolationError{Rule: PolicyRuleRecipientNotAllowed, Action: action}
+}
+
+func (engine *PolicyEngine) apply(action PolicyAction) error {
+	spending := &engine.spending
+	wallet := action.Wallet.String()
+	if action.IsLamports() {
+		if limit := engine.Policy.DailyLamportsLimit; limit > 0 && spending.TotalLamports+action.Amount > limit {
+			return &PolicyViolationError{Rule: PolicyRuleDailyLamports, Action: action, Limit: limit, Requested: action.Amount}
+		}
+		limit := engine.Policy.DefaultWalletDailyLamportsLimit
+		if walletLimit, ok := engine.Policy.WalletDailyLamportsLimits[wallet]; ok {
+			limit = walletLimit
+		}
+		if limit > 0 && spending.WalletLamports[action.Wallet]+action.Amount > limit {
+			return &PolicyViolationError{Rule: PolicyRuleWalletDailyLamports, Action: action, Limit: limit, Requested: action.Amount}
+		}
+		spending.TotalLamports += action.Amount
+		spending.WalletLamports[action.Wallet] += action.Amount
+		return nil
+	}
+	mint := action.Mint.String()
+	if limit := engine.Policy.DailyMintLimits[mint]; limit > 0 && spending.MintTotals[action.Mint]+action.Amount > limit {
+		return &PolicyViolationError{Rule: PolicyRuleDailyMint, Action: action, Limit: limit, Requested: action.Amount}
+	}
+	if spending.WalletMints[action.Wallet] == nil {
+		spending.WalletMints[action.Wallet] = map[solana.PublicKey]uint64{}
+	}
+	if limit := engine.Policy.WalletDailyMintLimits[wallet][mint]; limit > 0 &&
+		spending.WalletMints[action.Wallet][action.Mint]+action.Amount > limit {
+		return &PolicyViolationError{Rule: PolicyRuleWalletDailyMint, Action: action, Limit: limit, Requested: action.Amount}
+	}
+	spending.MintTotals[action.Mint] += action.Amount
+	spending.WalletMints[action.Wallet][action.Mint] += action.Amount
+	return nil
+}
+
+func (engine *PolicyEngine) revertAll(actions []PolicyAction) {
+	spending := &engine.spending
+	for _, action := range actions {
+		if action.IsLamports() {
+			spending.TotalLamports -= action.Amount
+			spending.WalletLamports[action.Wallet] -= action.Amount
+			continue
+		}
+		spending.MintTotals[action.Mint] -= action.Amount
+		spending.WalletMints[action.Wallet][action.Mint] -= action.Amount
+	}
+}
+
+func (engine *PolicyEngine) flush() error {
+	if engine.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(engine.spending, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(engine.path, data)
+}
@@ -0,0 +1,275 @@
+package wallet_manager
+
+import (
+	"encoding/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"sync"
+)
+
+type PolicyActionKind string
+
+const (
+	PolicyActionTransfer      PolicyActionKind = "transfer"
+	PolicyActionCreateAccount PolicyActionKind = "create_account"
+)
+
+// PolicyAction is a value-moving effect of a single instruction. Mint is zero
+// for native SOL, Recipient is the receiving wallet and zero when not applicable.
+type PolicyAction struct {
+	Kind      PolicyActionKind
+	Wallet    solana.PublicKey
+	Recipient solana.PublicKey
+	Mint      solana.PublicKey
+	Amount    uint64
+}
+
+func (action PolicyAction) IsLamports() bool {
+	return action.Mint.IsZero() || action.Mint.Equals(solana.SolMint)
+}
+
+// PolicyInstructionDecoder returns the value-moving effects of an instruction of a
+// program outside of the system and token programs.
+type PolicyInstructionDecoder func(accounts []*solana.AccountMeta, data []byte) ([]PolicyAction, error)
+
+var (
+	policyDecodersMu sync.RWMutex
+	policyDecoders   = map[solana.PublicKey]PolicyInstructionDecoder{}
+)
+
+// RegisterPolicyDecoder makes DecodePolicyActions decode instructions of programID
+// with decoder. Packages wrapping other programs register their decoders in init.
+func RegisterPolicyDecoder(programID solana.PublicKey, decoder PolicyInstructionDecoder) {
+	policyDecodersMu.Lock()
+	defer policyDecodersMu.Unlock()
+	policyDecoders[programID] = decoder
+}
+
+func policyDecoder(programID solana.PublicKey) (PolicyInstructionDecoder, bool) {
+	policyDecodersMu.RLock()
+	defer policyDecodersMu.RUnlock()
+	decoder, ok := policyDecoders[programID]
+	return decoder, ok
+}
+
+// DecodePolicyActions extracts SOL and token transfers, account funding, stake
+// withdrawals, token account closes and the actions of registered program decoders
+// from tx. Token account owners and mints are taken from associated token account
+// creations in the same transaction or fetched from the cluster.
+//
+// Withdrawals and closes paying back the wallet that controls the account are not
+// spending and are left out. Instructions that can not move value out of a wallet
+// on their own, such as token approvals, burns and stake delegation, are not
+// decoded, and neither are programs without a registered decoder.
+func (wm *WalletManager) DecodePolicyActions(tx *solana.Transaction) ([]PolicyAction, error) {
+	message := &tx.Message
+	createdTokenAccounts := map[solana.PublicKey]tokenAccountBalance{}
+	for _, instruction := range message.Instructions {
+		programID, err := message.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+		if err != nil || !programID.Equals(solana.SPLAssociatedTokenAccountProgramID) {
+			continue
+		}
+		accounts := instruction.ResolveInstructionAccounts(message)
+		if len(accounts) > 3 {
+			createdTokenAccounts[accounts[1].PublicKey] = tokenAccountBalance{
+				Owner: accounts[2].PublicKey,
+				Mint:  accounts[3].PublicKey,
+			}
+		}
+	}
+	resolveTokenAccount := func(address solana.PublicKey) (tokenAccountBalance, error) {
+		if created, ok := createdTokenAccounts[address]; ok {
+			return created, nil
+		}
+		info, err := wm.Client.GetAccountInfoWithOpts(wm.Context, address, &rpc.GetAccountInfoOpts{
+			Commitment: wm.Commitment,
+		})
+		if err != nil {
+			return tokenAccountBalance{}, errors.Errorf("failed to get token account %s. err: %s", address.String(), err.Error())
+		}
+		balance, ok := decodeTokenAccount(info.Value)
+		if !ok {
+			return tokenAccountBalance{}, errors.Errorf("%s is not a token account", address.String())
+		}
+		return balance, nil
+	}
+	accountLamports := func(address solana.PublicKey) (uint64, error) {
+		if _, ok := createdTokenAccounts[address]; ok {
+			// funded by the creation in the same transaction
+			return 0, nil
+		}
+		info, err := wm.Client.GetAccountInfoWithOpts(wm.Context, address, &rpc.GetAccountInfoOpts{
+			Commitment: wm.Commitment,
+		})
+		if err != nil {
+			return 0, errors.Errorf("failed to get account %s. err: %s", address.String(), err.Error())
+		}
+		return info.Value.Lamports, nil
+	}
+
+	var actions []PolicyAction
+	for _, instruction := range message.Instructions {
+		programID, err := message.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+		if err != nil {
+			return nil, err
+		}
+		accounts := instruction.ResolveInstructionAccounts(message)
+		switch {
+		case programID.Equals(solana.SystemProgramID):
+			decoded, err := system.DecodeInstruction(accounts, instruction.Data)
+			if err != nil {
+				return nil, errors.Errorf("failed to decode system instruction. err: %s", err.Error())
+			}
+			switch impl := decoded.Impl.(type) {
+			case *system.Transfer:
+				actions = append(actions, PolicyAction{
+					Kind:      PolicyActionTransfer,
+					Wallet:    impl.GetFundingAccount().PublicKey,
+					Recipient: impl.GetRecipientAccount().PublicKey,
+					Amount:    *impl.Lamports,
+				})
+			case *system.TransferWithSeed:
+				actions = append(actions, PolicyAction{
+					Kind:      PolicyActionTransfer,
+					Wallet:    impl.GetFundingAccount().PublicKey,
+					Recipient: impl.GetRecipientAccount().PublicKey,
+					Amount:    *impl.Lamports,
+				})
+			case *system.CreateAccount:
+				actions = append(actions, PolicyAction{
+					Kind:   PolicyActionCreateAccount,
+					Wallet: impl.GetFundingAccount().PublicKey,
+					Amount: *impl.Lamports,
+				})
+			case *system.CreateAccountWithSeed:
+				actions = append(actions, PolicyAction{
+					Kind:   PolicyActionCreateAccount,
+					Wallet: impl.GetFundingAccount().PublicKey,
+					Amount: *impl.Lamports,
+				})
+			}
+		case programID.Equals(solana.TokenProgramID):
+			decoded, err := token.DecodeInstruction(accounts, instruction.Data)
+			if err != nil {
+				return nil, errors.Errorf("failed to decode token instruction. err: %s", err.Error())
+			}
+			var owner, destination solana.PublicKey
+			var amount uint64
+			switch impl := decoded.Impl.(type) {
+			case *token.Transfer:
+				owner, destination, amount = impl.GetOwnerAccount().PublicKey, impl.GetDestinationAccount().PublicKey, *impl.Amount
+			case *token.TransferChecked:
+				owner, destination, amount = impl.GetOwnerAccount().PublicKey, impl.GetDestinationAccount().PublicKey, *impl.Amount
+			case *token.CloseAccount:
+				owner, destination = impl.GetOwnerAccount().PublicKey, impl.GetDestinationAccount().PublicKey
+				if destination.Equals(owner) {
+					continue
+				}
+				lamports, err := accountLamports(impl.GetAccount().PublicKey)
+				if err != nil {
+					return nil, err
+				}
+				actions = append(actions, PolicyAction{
+					Kind:      PolicyActionTransfer,
+					Wallet:    owner,
+					Recipient: destination,
+					Amount:    lamports,
+				})
+				continue
+			default:
+				continue
+			}
+			destinationAccount, err := resolveTokenAccount(destination)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, PolicyAction{
+				Kind:      PolicyActionTransfer,
+				Wallet:    owner,
+				Recipient: destinationAccount.Owner,
+				Mint:      destinationAccount.Mint,
+				Amount:    amount,
+			})
+		case programID.Equals(StakeProgramID):
+			if action, ok := decodeStakeWithdraw(accounts, instruction.Data); ok {
+				actions = append(actions, action)
+			}
+		default:
+			decoder, ok := policyDecoder(programID)
+			if !ok {
+				continue
+			}
+			decoded, err := decoder(accounts, instruction.Data)
+			if err != nil {
+				return nil, errors.Errorf("failed to decode %s instruction. err: %s", programID.String(), err.Error())
+			}
+			actions = append(actions, decoded...)
+		}
+	}
+	return actions, nil
+}
+
+// decodeStakeWithdraw reports a stake withdrawal to another wallet as spending of
+// the withdraw authority.
+func decodeStakeWithdraw(accounts []*solana.AccountMeta, data []byte) (PolicyAction, bool) {
+	if len(data) < 12 || len(accounts) < 5 || binary.LittleEndian.Uint32(data) != stakeInstructionWithdraw {
+		return PolicyAction{}, false
+	}
+	authority, recipient := accounts[4].PublicKey, accounts[1].PublicKey
+	if recipient.Equals(authority) {
+		return PolicyAction{}, false
+	}
+	return PolicyAction{
+		Kind:      PolicyActionTransfer,
+		Wallet:    authority,
+		Recipient: recipient,
+		Amount:    binary.LittleEndian.Uint64(data[4:12]),
+	}, true
+}
+
+// policyReservation is the spending reserved for one transaction. It is kept once
+// the transaction may have reached the cluster and released otherwise.
+type policyReservation struct {
+	release func()
+	sent    bool
+	done    bool
+}
+
+func (reservation *policyReservation) finish() {
+	if reservation == nil || reservation.done {
+		return
+	}
+	reservation.done = true
+	if !reservation.sent {
+		reservation.release()
+	}
+}
+
+// reservePolicy checks tx against wm.Policy and returns a copy of wm holding the
+// reservation. The caller has to finish it once the transaction went out or failed.
+func (wm *WalletManager) reservePolicy(tx *solana.Transaction) (*WalletManager, error) {
+	release := func() {}
+	if wm.Policy != nil {
+		actions, err := wm.DecodePolicyActions(tx)
+		if err != nil {
+			return nil, err
+		}
+		release, err = wm.Policy.Reserve(actions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	reserved := *wm
+	reserved.policyReservation = &policyReservation{release: release}
+	return &reserved, nil
+}
+
+// markPolicySent keeps the reserved spending, the transaction may land from now on.
+func (wm *WalletManager) markPolicySent() {
+	if wm.policyReservation != nil {
+		wm.policyReservation.sent = true
+	}
+}
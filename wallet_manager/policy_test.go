@@ -0,0 +1,225 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+	"time"
+)
+
+func TestPolicyEngine_DailyLimits(t *testing.T) {
+	wallet := solana.NewWallet().PublicKey()
+	recipient := solana.NewWallet().PublicKey()
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	engine := NewPolicyEngine(SpendingPolicy{
+		DefaultWalletDailyLamportsLimit: 1000,
+	})
+	engine.Now = func() time.Time { return now }
+	transfer := PolicyAction{Kind: PolicyActionTransfer, Wallet: wallet, Recipient: recipient, Amount: 600}
+
+	if _, err := engine.Reserve([]PolicyAction{transfer}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := engine.Reserve([]PolicyAction{transfer})
+	violation, ok := err.(*PolicyViolationError)
+	if !ok || violation.Rule != PolicyRuleWalletDailyLamports {
+		t.Fatalf("expected wallet daily limit violation, got %v", err)
+	}
+
+	engine.AddCheck(func(action PolicyAction) error {
+		if action.Kind == "custom" {
+			return &PolicyViolationError{Rule: "custom", Action: action}
+		}
+		return nil
+	})
+	custom := PolicyAction{Kind: "custom", Wallet: wallet, Amount: 1}
+	if _, err = engine.Reserve([]PolicyAction{custom}); !IsPolicyViolation(err) {
+		t.Fatalf("expected registered check violation, got %v", err)
+	}
+
+	now = now.Add(24 * time.Hour)
+	release, err := engine.Reserve([]PolicyAction{transfer})
+	if err != nil {
+		t.Fatalf("limit should reset on the next day. err: %s", err)
+	}
+	release()
+	if spent := engine.SpentLamports(wallet); spent != 0 {
+		t.Fatalf("released reservation should not count, spent %d", spent)
+	}
+}
+
+func TestWalletManager_PolicyRejectsBeforeSigning(t *testing.T) {
+	denied := solana.NewWallet().PublicKey()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	err := os.WriteFile(path, []byte(`{"deniedRecipients": ["`+denied.String()+`"]}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadSpendingPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := fakerpc.New()
+	manager := newFakeWalletManager(fake)
+	manager.Policy = NewPolicyEngine(policy)
+
+	_, err = manager.SendLamports(solana.NewWallet().PrivateKey, denied, 1000)
+	var violation *PolicyViolationError
+	if !errors.As(err, &violation) || violation.Rule != PolicyRuleDeniedRecipient {
+		t.Fatalf("expected denied recipient violation, got %v", err)
+	}
+	if fake.Calls("sendTransaction") != 0 {
+		t.Fatal("rejected transaction must not be sent")
+	}
+}
+
+func TestPolicyEngine_PersistsSpending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spending.json")
+	wallet := solana.NewWallet().PublicKey()
+	policy := SpendingPolicy{DefaultWalletDailyLamportsLimit: 1000}
+	engine, err := OpenPolicyEngine(path, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer := PolicyAction{Kind: PolicyActionTransfer, Wallet: wallet, Amount: 600}
+	if _, err = engine.Reserve([]PolicyAction{transfer}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenPolicyEngine(path, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent := reopened.SpentLamports(wallet); spent != 600 {
+		t.Fatalf("expected spending to survive a restart, got %d", spent)
+	}
+	if _, err = reopened.Reserve([]PolicyAction{transfer}); !IsPolicyViolation(err) {
+		t.Fatalf("expected the persisted spending to count, got %v", err)
+	}
+}
+
+func TestWalletManager_PolicyReleasedWhenNotSent(t *testing.T) {
+	fake := fakerpc.New()
+	fake.Handle("sendTransaction", func(params []interface{}) (interface{}, error) {
+		return nil, &jsonrpc.RPCError{Code: -32002, Message: "Transaction simulation failed"}
+	})
+	manager := newFakeWalletManager(fake)
+	manager.Policy = NewPolicyEngine(SpendingPolicy{DefaultWalletDailyLamportsLimit: 1000})
+	from := solana.NewWallet().PrivateKey
+
+	if _, err := manager.SendLamports(from, solana.NewWallet().PublicKey(), 600); err == nil {
+		t.Fatal("expected the rejected send to fail")
+	}
+	if spent := manager.Policy.SpentLamports(from.PublicKey()); spent != 0 {
+		t.Fatalf("a rejected transaction must not count, spent %d", spent)
+	}
+
+	ptx, err := manager.PrepareInstructions(
+		from.PublicKey(),
+		[]solana.Instruction{makeTransferInstruction(from.PublicKey(), solana.NewWallet().PublicKey(), 600)},
+		[]solana.PrivateKey{from},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent := manager.Policy.SpentLamports(from.PublicKey()); spent != 600 {
+		t.Fatalf("a prepared transaction should reserve its spending, spent %d", spent)
+	}
+	ptx.Discard()
+	if spent := manager.Policy.SpentLamports(from.PublicKey()); spent != 0 {
+		t.Fatalf("a discarded transaction must not count, spent %d", spent)
+	}
+}
+
+func TestWalletManager_PolicyAppliesToSubmittedTransactions(t *testing.T) {
+	denied := solana.NewWallet().PublicKey()
+	from := solana.NewWallet().PrivateKey
+	fake := fakerpc.New()
+	manager := newFakeWalletManager(fake)
+	tx, err := manager.BuildTransaction(from.PublicKey(), []solana.Instruction{makeTransferInstruction(from.PublicKey(), denied, 1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = SignTransaction(tx, []solana.PrivateKey{from}); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ExportTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.Policy = NewPolicyEngine(SpendingPolicy{DeniedRecipients: []string{denied.String()}})
+
+	if _, err = manager.SubmitExportedTransaction(exported); !IsPolicyViolation(err) {
+		t.Fatalf("expected denied recipient violation, got %v", err)
+	}
+	if fake.Calls("sendTransaction") != 0 {
+		t.Fatal("rejected transaction must not be sent")
+	}
+}
+
+func TestWalletManager_DecodePolicyActionsWithdrawals(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	recipient := solana.NewWallet().PublicKey()
+	stake := solana.NewWallet().PublicKey()
+	fake := fakerpc.New()
+	manager := newFakeWalletManager(fake)
+	ata, _, err := solana.FindAssociatedTokenAddress(owner, solana.WrappedSol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.SetTokenAccount(ata, solana.WrappedSol, owner, 5000)
+	unwrapToOwner, err := UnwrapSolInstructions(owner, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unwrapToRecipient, err := UnwrapSolInstructions(owner, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instructions := append(unwrapToOwner, unwrapToRecipient...)
+	instructions = append(
+		instructions,
+		NewStakeWithdrawInstruction(stake, owner, owner, 700),
+		NewStakeWithdrawInstruction(stake, recipient, owner, 800),
+	)
+	tx, err := manager.BuildTransaction(owner, instructions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions, err := manager.DecodePolicyActions(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PolicyAction{
+		{Kind: PolicyActionTransfer, Wallet: owner, Recipient: recipient, Amount: fakerpc.TokenAccountLamports},
+		{Kind: PolicyActionTransfer, Wallet: owner, Recipient: recipient, Amount: 800},
+	}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected withdrawals to the owner to be left out, got %+v", actions)
+	}
+	for i := range expected {
+		if actions[i] != expected[i] {
+			t.Fatalf("expected %+v, got %+v", expected[i], actions[i])
+		}
+	}
+}
+
+func TestPolicyEngine_SetCheck(t *testing.T) {
+	engine := NewPolicyEngine(SpendingPolicy{})
+	reject := func(action PolicyAction) error {
+		return &PolicyViolationError{Rule: "custom", Action: action}
+	}
+	engine.SetCheck("custom", reject)
+	engine.SetCheck("custom", func(PolicyAction) error { return nil })
+	if len(engine.Checks) != 1 {
+		t.Fatalf("expected the named check to be replaced, got %d checks", len(engine.Checks))
+	}
+	if _, err := engine.Reserve([]PolicyAction{{Kind: "custom", Amount: 1}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,230 @@
+package wallet_manager
+
+import (
+	"context"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"golang.org/x/time/rate"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+type RateLimitOpts struct {
+	Endpoint RateLimit
+	Methods  map[string]RateLimit
+}
+
+type RateLimitStats struct {
+	Endpoint  string
+	Method    string
+	Waiting   int
+	Calls     uint64
+	TotalWait time.Duration
+}
+
+// RateLimiter keeps token buckets per endpoint and per (endpoint, method) pair.
+// Clients created for the same endpoint share buckets, so several WalletManager
+// instances talking to one provider stay under a single quota.
+type RateLimiter struct {
+	Now   func() time.Time
+	After func(d time.Duration) <-chan time.Time
+
+	opts    RateLimitOpts
+	mu      sync.Mutex
+	buckets map[rateLimitKey]*rateLimitBucket
+}
+
+type rateLimitKey struct {
+	endpoint string
+	method   string
+}
+
+type rateLimitBucket struct {
+	limiter   *rate.Limiter
+	waiting   int
+	calls     uint64
+	totalWait time.Duration
+}
+
+func NewRateLimiter(opts RateLimitOpts) *RateLimiter {
+	return &RateLimiter{
+		Now:     time.Now,
+		After:   time.After,
+		opts:    opts,
+		buckets: map[rateLimitKey]*rateLimitBucket{},
+	}
+}
+
+func NewRateLimitedClient(endpoint string, limiter *RateLimiter) *rpc.Client {
+	return rpc.NewWithCustomRPCClient(limiter.Wrap(endpoint, jsonrpc.NewClient(endpoint)))
+}
+
+func (rl *RateLimiter) Wrap(endpoint string, client rpc.JSONRPCClient) rpc.JSONRPCClient {
+	return &rateLimitedRPCClient{
+		endpoint:  endpoint,
+		rpcClient: client,
+		limiter:   rl,
+	}
+}
+
+// Wait blocks until both the method and the endpoint-wide bucket allow a call.
+// The method bucket is waited on first and the endpoint token is only reserved
+// afterwards, so a call delayed by its method does not hold endpoint quota. A
+// cancelled ctx gives back the token the call is waiting for.
+func (rl *RateLimiter) Wait(ctx context.Context, endpoint, method string) error {
+	var buckets []*rateLimitBucket
+	if bucket := rl.bucket(endpoint, method); bucket != nil {
+		buckets = append(buckets, bucket)
+	}
+	if bucket := rl.bucket(endpoint, ""); bucket != nil {
+		buckets = append(buckets, bucket)
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+	started := rl.Now()
+	rl.mu.Lock()
+	for _, bucket := range buckets {
+		bucket.waiting++
+	}
+	rl.mu.Unlock()
+
+	var err error
+	for _, bucket := range buckets {
+		if err = rl.waitBucket(ctx, bucket); err != nil {
+			break
+		}
+	}
+	now := rl.Now()
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for _, bucket := range buckets {
+		bucket.waiting--
+		bucket.totalWait += now.Sub(started)
+		if err == nil {
+			bucket.calls++
+		}
+	}
+	return err
+}
+
+func (rl *RateLimiter) waitBucket(ctx context.Context, bucket *rateLimitBucket) error {
+	now := rl.Now()
+	rl.mu.Lock()
+	reservation := bucket.limiter.ReserveN(now, 1)
+	rl.mu.Unlock()
+	delay := reservation.DelayFrom(now)
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-rl.After(delay):
+		return nil
+	case <-ctx.Done():
+		rl.mu.Lock()
+		reservation.CancelAt(rl.Now())
+		rl.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (rl *RateLimiter) Stats() []RateLimitStats {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	var stats []RateLimitStats
+	for key, bucket := range rl.buckets {
+		stats = append(stats, RateLimitStats{
+			Endpoint:  key.endpoint,
+			Method:    key.method,
+			Waiting:   bucket.waiting,
+			Calls:     bucket.calls,
+			TotalWait: bucket.totalWait,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Endpoint != stats[j].Endpoint {
+			return stats[i].Endpoint < stats[j].Endpoint
+		}
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+func (rl *RateLimiter) QueueDepth(endpoint string) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	depth := 0
+	for key, bucket := range rl.buckets {
+		if key.endpoint == endpoint {
+			depth += bucket.waiting
+		}
+	}
+	return depth
+}
+
+func (rl *RateLimiter) bucket(endpoint, method string) *rateLimitBucket {
+	limit := rl.opts.Endpoint
+	if method != "" {
+		var ok bool
+		limit, ok = rl.opts.Methods[method]
+		if !ok {
+			return nil
+		}
+	}
+	if limit.RequestsPerSecond <= 0 {
+		return nil
+	}
+	key := rateLimitKey{endpoint, method}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		bucket = &rateLimitBucket{limiter: rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), burst)}
+		rl.buckets[key] = bucket
+	}
+	return bucket
+}
+
+type rateLimitedRPCClient struct {
+	endpoint  string
+	rpcClient rpc.JSONRPCClient
+	limiter   *RateLimiter
+}
+
+func (c *rateLimitedRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	if err := c.limiter.Wait(ctx, c.endpoint, method); err != nil {
+		return err
+	}
+	return c.rpcClient.CallForInto(ctx, out, method, params)
+}
+
+func (c *rateLimitedRPCClient) CallWithCallback(
+	ctx context.Context,
+	method string,
+	params []interface{},
+	callback func(*http.Request, *http.Response) error,
+) error {
+	if err := c.limiter.Wait(ctx, c.endpoint, method); err != nil {
+		return err
+	}
+	return c.rpcClient.CallWithCallback(ctx, method, params, callback)
+}
+
+func (c *rateLimitedRPCClient) Close() error {
+	if closer, ok := c.rpcClient.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
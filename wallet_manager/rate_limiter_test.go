@@ -0,0 +1,170 @@
+package wallet_manager
+
+import (
+	"context"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingRPCClient struct {
+	calls int32
+}
+
+func (c *countingRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	atomic.AddInt32(&c.calls, 1)
+	return nil
+}
+
+func (c *countingRPCClient) CallWithCallback(
+	ctx context.Context,
+	method string,
+	params []interface{},
+	callback func(*http.Request, *http.Response) error,
+) error {
+	atomic.AddInt32(&c.calls, 1)
+	return nil
+}
+
+// fakeClock sleeps by moving its time forward. While blocked it reports every
+// wait on waits and never fires, so only a cancelled context ends the wait.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	slept   time.Duration
+	blocked bool
+	waits   chan time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), waits: make(chan time.Duration, 1)}
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *fakeClock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	if clock.blocked {
+		clock.waits <- d
+		return nil
+	}
+	clock.now = clock.now.Add(d)
+	clock.slept += d
+	fired := make(chan time.Time, 1)
+	fired <- clock.now
+	return fired
+}
+
+func (clock *fakeClock) sleptFor() time.Duration {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.slept
+}
+
+func newFakeClockRateLimiter(opts RateLimitOpts) (*RateLimiter, *fakeClock) {
+	clock := newFakeClock()
+	limiter := NewRateLimiter(opts)
+	limiter.Now = clock.Now
+	limiter.After = clock.After
+	return limiter, clock
+}
+
+func TestRateLimiter_MethodQuota(t *testing.T) {
+	limiter, clock := newFakeClockRateLimiter(RateLimitOpts{
+		Methods: map[string]RateLimit{
+			"getProgramAccounts": {RequestsPerSecond: 5, Burst: 1},
+		},
+	})
+	inner := &countingRPCClient{}
+	client := rpc.NewWithCustomRPCClient(limiter.Wrap("test", inner))
+
+	for i := 0; i < 3; i++ {
+		_, _ = client.GetProgramAccounts(ctx, solana.SystemProgramID)
+	}
+	if slept := clock.sleptFor(); slept != 400*time.Millisecond {
+		t.Fatalf("expected getProgramAccounts to wait 400ms, waited %s", slept)
+	}
+
+	for i := 0; i < 10; i++ {
+		_, _ = client.GetBalance(ctx, solana.SystemProgramID, rpc.CommitmentConfirmed)
+	}
+	if slept := clock.sleptFor(); slept != 400*time.Millisecond {
+		t.Fatalf("getBalance should not be throttled, waited %s in total", slept)
+	}
+	if inner.calls != 13 {
+		t.Fatalf("expected 13 calls, got %d", inner.calls)
+	}
+}
+
+func TestRateLimiter_ContextCancel(t *testing.T) {
+	limiter, clock := newFakeClockRateLimiter(RateLimitOpts{
+		Endpoint: RateLimit{RequestsPerSecond: 0.1, Burst: 1},
+		Methods: map[string]RateLimit{
+			"getBalance": {RequestsPerSecond: 0.1, Burst: 1},
+		},
+	})
+	if err := limiter.Wait(ctx, "test", "getBalance"); err != nil {
+		t.Fatal(err)
+	}
+	clock.blocked = true
+	waitCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- limiter.Wait(waitCtx, "test", "getBalance")
+	}()
+	if delay := <-clock.waits; delay != 10*time.Second {
+		t.Fatalf("expected a 10s wait, got %s", delay)
+	}
+	if depth := limiter.QueueDepth("test"); depth != 2 {
+		t.Fatalf("expected both buckets to have a waiter, got %d", depth)
+	}
+	cancel()
+	if err := <-done; err == nil {
+		t.Fatal("expected wait to fail on cancelled context")
+	}
+	stats := limiter.Stats()
+	if len(stats) != 2 || stats[0].Calls != 1 || stats[1].Calls != 1 || stats[0].Waiting != 0 || stats[1].Waiting != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// Had either bucket kept the token of the cancelled call, the next call would
+	// have to wait 20s.
+	clock.blocked = false
+	if err := limiter.Wait(ctx, "test", "getBalance"); err != nil {
+		t.Fatal(err)
+	}
+	if slept := clock.sleptFor(); slept != 10*time.Second {
+		t.Fatalf("expected the cancelled tokens to be given back, waited %s", slept)
+	}
+}
+
+func TestRateLimiter_MethodWaitKeepsEndpointQuota(t *testing.T) {
+	limiter, clock := newFakeClockRateLimiter(RateLimitOpts{
+		Endpoint: RateLimit{RequestsPerSecond: 1, Burst: 1},
+		Methods: map[string]RateLimit{
+			"getBalance": {RequestsPerSecond: 0.5, Burst: 1},
+		},
+	})
+	var calls []time.Time
+	for _, method := range []string{"getBalance", "getBalance", "getSlot"} {
+		if err := limiter.Wait(ctx, "test", method); err != nil {
+			t.Fatal(err)
+		}
+		calls = append(calls, clock.Now())
+	}
+	// The second getBalance waits 2s on its method quota and only then takes an
+	// endpoint token, so getSlot has to wait for the next one.
+	start := calls[0]
+	if calls[1].Sub(start) != 2*time.Second || calls[2].Sub(start) != 3*time.Second {
+		t.Fatalf("expected calls at +2s and +3s, got +%s and +%s", calls[1].Sub(start), calls[2].Sub(start))
+	}
+}
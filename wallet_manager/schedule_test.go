@@ -0,0 +1,35 @@
+package wallet_manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	cases := []struct {
+		spec  string
+		after time.Time
+		next  time.Time
+	}{
+		{"0 9 * * 1", time.Date(2022, 10, 3, 9, 0, 0, 0, time.UTC), time.Date(2022, 10, 10, 9, 0, 0, 0, time.UTC)},
+		{"*/15 8-18 * * 1-5", time.Date(2022, 10, 7, 18, 50, 0, 0, time.UTC), time.Date(2022, 10, 10, 8, 0, 0, 0, time.UTC)},
+		{"30 12 1,15 * *", time.Date(2022, 10, 2, 0, 0, 0, 0, time.UTC), time.Date(2022, 10, 15, 12, 30, 0, 0, time.UTC)},
+		{"0 0 31 * 7", time.Date(2022, 10, 3, 0, 0, 0, 0, time.UTC), time.Date(2022, 10, 9, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2022, 12, 5, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, c := range cases {
+		schedule, err := ParseSchedule(c.spec)
+		if err != nil {
+			t.Fatalf("failed to parse %q. err: %s", c.spec, err.Error())
+		}
+		if next := schedule.Next(c.after); !next.Equal(c.next) {
+			t.Fatalf("%q after %s: expected %s, got %s", c.spec, c.after, c.next, next)
+		}
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Fatalf("expected %q to be rejected", spec)
+		}
+	}
+}
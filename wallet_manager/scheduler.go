@@ -0,0 +1,433 @@
+package wallet_manager
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"os"
+	"solana-go-wm/internal/atomicfile"
+	"sync"
+	"time"
+)
+
+type MissedRunPolicy string
+
+const (
+	// MissedRunSkip records missed runs as skipped without sending anything.
+	MissedRunSkip MissedRunPolicy = "skip"
+	// MissedRunCatchUp executes every missed run in order.
+	MissedRunCatchUp MissedRunPolicy = "catch_up"
+	// MissedRunOnce executes only the latest of the missed runs.
+	MissedRunOnce MissedRunPolicy = "run_once"
+)
+
+type ScheduledRunStatus string
+
+const (
+	ScheduledRunSucceeded ScheduledRunStatus = "succeeded"
+	ScheduledRunFailed    ScheduledRunStatus = "failed"
+	ScheduledRunSkipped   ScheduledRunStatus = "skipped"
+)
+
+const (
+	defaultSchedulerGracePeriod  = time.Minute
+	defaultSchedulerMaxRetries   = 3
+	defaultSchedulerRetryBackoff = time.Minute
+	defaultSchedulerHistoryLimit = 10000
+	maxScheduledRunsPerJob       = 1000
+)
+
+var ErrScheduledJobNotFound = errors.New("scheduled job not found")
+
+// RecurringTransfer sends Amount lamports, or Amount tokens of Mint when it is set,
+// from From to To on every activation of Schedule.
+type RecurringTransfer struct {
+	ID              string           `json:"id"`
+	Schedule        string           `json:"schedule"`
+	From            solana.PublicKey `json:"from"`
+	To              solana.PublicKey `json:"to"`
+	Mint            solana.PublicKey `json:"mint"`
+	Amount          uint64           `json:"amount"`
+	Memo            string           `json:"memo,omitempty"`
+	MissedRunPolicy MissedRunPolicy  `json:"missedRunPolicy"`
+	Paused          bool             `json:"paused"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	NextRun         time.Time        `json:"nextRun"`
+	// Attempts counts failed attempts of the run at NextRun, which is retried
+	// at RetryAt.
+	Attempts int       `json:"attempts,omitempty"`
+	RetryAt  time.Time `json:"retryAt,omitempty"`
+}
+
+func (job RecurringTransfer) IsLamports() bool {
+	return job.Mint.IsZero()
+}
+
+type ScheduledRun struct {
+	JobID          string             `json:"jobId"`
+	ScheduledAt    time.Time          `json:"scheduledAt"`
+	ExecutedAt     time.Time          `json:"executedAt"`
+	Status         ScheduledRunStatus `json:"status"`
+	Attempt        int                `json:"attempt,omitempty"`
+	IdempotencyKey string             `json:"idempotencyKey,omitempty"`
+	Signature      string             `json:"signature,omitempty"`
+	Error          string             `json:"error,omitempty"`
+}
+
+type schedulerState struct {
+	Jobs    []*RecurringTransfer `json:"jobs"`
+	History []ScheduledRun       `json:"history"`
+}
+
+// Scheduler stores recurring transfers and their run history in a local JSON file.
+// Transfers are sent through the idempotent send path, so a run interrupted by a
+// crash is replayed instead of being paid twice. The scheduler does not own a
+// timer: call RunDue periodically.
+type Scheduler struct {
+	Manager *WalletManager
+	Signer  func(wallet solana.PublicKey) (solana.PrivateKey, error)
+	Now     func() time.Time
+	// GracePeriod is how late a run may be started before it counts as missed.
+	GracePeriod time.Duration
+	Location    *time.Location
+	// MaxRetries is how many times a failed run is retried, the delay before a
+	// retry starts at RetryBackoff and doubles with every attempt.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// HistoryLimit is how many of the latest runs are kept, zero keeps all.
+	HistoryLimit int
+
+	path      string
+	runMu     sync.Mutex
+	mu        sync.Mutex
+	state     schedulerState
+	schedules map[string]*Schedule
+}
+
+type scheduledJobRuns struct {
+	job  RecurringTransfer
+	runs []plannedRun
+}
+
+type plannedRun struct {
+	at      time.Time
+	execute bool
+	attempt int
+	key     string
+}
+
+func OpenScheduler(
+	path string,
+	wm *WalletManager,
+	signer func(wallet solana.PublicKey) (solana.PrivateKey, error),
+) (*Scheduler, error) {
+	scheduler := &Scheduler{
+		Manager:      wm,
+		Signer:       signer,
+		Now:          time.Now,
+		GracePeriod:  defaultSchedulerGracePeriod,
+		Location:     time.UTC,
+		MaxRetries:   defaultSchedulerMaxRetries,
+		RetryBackoff: defaultSchedulerRetryBackoff,
+		HistoryLimit: defaultSchedulerHistoryLimit,
+		path:         path,
+		schedules:    map[string]*Schedule{},
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return scheduler, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("failed to read scheduler %s. err: %s", path, err.Error())
+	}
+	if err = json.Unmarshal(data, &scheduler.state); err != nil {
+		return nil, errors.Errorf("failed to parse scheduler %s. err: %s", path, err.Error())
+	}
+	for _, job := range scheduler.state.Jobs {
+		schedule, err := ParseSchedule(job.Schedule)
+		if err != nil {
+			return nil, errors.Errorf("invalid schedule of job %s. err: %s", job.ID, err.Error())
+		}
+		scheduler.schedules[job.ID] = schedule
+	}
+	return scheduler, nil
+}
+
+func (scheduler *Scheduler) AddJob(job RecurringTransfer) (RecurringTransfer, error) {
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return RecurringTransfer{}, err
+	}
+	if job.Amount == 0 {
+		return RecurringTransfer{}, errors.New("scheduled transfer amount is zero")
+	}
+	if job.MissedRunPolicy == "" {
+		job.MissedRunPolicy = MissedRunSkip
+	}
+	switch job.MissedRunPolicy {
+	case MissedRunSkip, MissedRunCatchUp, MissedRunOnce:
+	default:
+		return RecurringTransfer{}, errors.Errorf("unknown missed run policy %s", job.MissedRunPolicy)
+	}
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if job.ID == "" {
+		idBytes := make([]byte, 8)
+		if _, err = rand.Read(idBytes); err != nil {
+			return RecurringTransfer{}, err
+		}
+		job.ID = hex.EncodeToString(idBytes)
+	}
+	if scheduler.job(job.ID) != nil {
+		return RecurringTransfer{}, errors.Errorf("scheduled job %s already exists", job.ID)
+	}
+	now := scheduler.now()
+	job.CreatedAt = now
+	job.NextRun = schedule.Next(now)
+	scheduler.state.Jobs = append(scheduler.state.Jobs, &job)
+	scheduler.schedules[job.ID] = schedule
+	if err = scheduler.flush(); err != nil {
+		scheduler.state.Jobs = scheduler.state.Jobs[:len(scheduler.state.Jobs)-1]
+		delete(scheduler.schedules, job.ID)
+		return RecurringTransfer{}, err
+	}
+	return job, nil
+}
+
+func (scheduler *Scheduler) RemoveJob(id string) error {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	for idx, job := range scheduler.state.Jobs {
+		if job.ID == id {
+			scheduler.state.Jobs = append(scheduler.state.Jobs[:idx], scheduler.state.Jobs[idx+1:]...)
+			delete(scheduler.schedules, id)
+			return scheduler.flush()
+		}
+	}
+	return errors.Wrap(ErrScheduledJobNotFound, id)
+}
+
+// SetPaused pauses or resumes a job. Runs that fall into a pause are handled by
+// the job's missed run policy once it is resumed.
+func (scheduler *Scheduler) SetPaused(id string, paused bool) error {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	job := scheduler.job(id)
+	if job == nil {
+		return errors.Wrap(ErrScheduledJobNotFound, id)
+	}
+	job.Paused = paused
+	return scheduler.flush()
+}
+
+func (scheduler *Scheduler) Jobs() []RecurringTransfer {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	var jobs []RecurringTransfer
+	for _, job := range scheduler.state.Jobs {
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+// History returns runs of the job with id, or of all jobs when id is empty.
+func (scheduler *Scheduler) History(id string) []ScheduledRun {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	var runs []ScheduledRun
+	for _, run := range scheduler.state.History {
+		if id == "" || run.JobID == id {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// RunDue executes every run which is due at Now and returns the recorded runs.
+// Due runs are collected under the lock and sent without it, so the scheduler
+// stays usable while transfers confirm. A failed run is retried with backoff up
+// to MaxRetries times before the job moves on to its next run. Every attempt of a
+// run uses the same idempotency key, so a retry replays a transfer that was sent
+// before instead of paying it twice.
+func (scheduler *Scheduler) RunDue() ([]ScheduledRun, error) {
+	if scheduler.Manager.Journal == nil {
+		return nil, errors.New("scheduler requires wm.Journal for idempotent sends")
+	}
+	scheduler.runMu.Lock()
+	defer scheduler.runMu.Unlock()
+	scheduler.mu.Lock()
+	now := scheduler.now()
+	due := scheduler.dueRuns(now)
+	scheduler.mu.Unlock()
+
+	var runs []ScheduledRun
+	for _, jobRuns := range due {
+		for _, planned := range jobRuns.runs {
+			run := ScheduledRun{
+				JobID:       jobRuns.job.ID,
+				ScheduledAt: planned.at,
+				ExecutedAt:  now,
+				Status:      ScheduledRunSkipped,
+			}
+			if planned.execute {
+				run = scheduler.execute(&jobRuns.job, planned, now)
+			}
+			scheduler.mu.Lock()
+			wait, flushErr := scheduler.record(run, now)
+			scheduler.mu.Unlock()
+			if flushErr != nil {
+				return runs, flushErr
+			}
+			runs = append(runs, run)
+			if wait {
+				break
+			}
+		}
+	}
+	return runs, nil
+}
+
+func (scheduler *Scheduler) dueRuns(now time.Time) []scheduledJobRuns {
+	var due []scheduledJobRuns
+	for _, job := range scheduler.state.Jobs {
+		if job.Paused || job.NextRun.IsZero() || job.NextRun.After(now) || job.RetryAt.After(now) {
+			continue
+		}
+		schedule := scheduler.schedules[job.ID]
+		var times []time.Time
+		lastMissed := -1
+		for at := job.NextRun.In(now.Location()); !at.IsZero() && !at.After(now) && len(times) < maxScheduledRunsPerJob; at = schedule.Next(at) {
+			if now.Sub(at) > scheduler.GracePeriod {
+				lastMissed = len(times)
+			}
+			times = append(times, at)
+		}
+		jobRuns := scheduledJobRuns{job: *job}
+		for idx, at := range times {
+			missed := idx <= lastMissed
+			planned := plannedRun{
+				at:      at,
+				attempt: 1,
+				key:     scheduledRunKey(job.ID, at),
+			}
+			planned.execute = !missed || job.MissedRunPolicy == MissedRunCatchUp ||
+				(job.MissedRunPolicy == MissedRunOnce && idx == lastMissed)
+			if idx == 0 && job.Attempts > 0 {
+				// a retried run is late by design and is not treated as missed
+				planned.execute = true
+				planned.attempt = job.Attempts + 1
+			}
+			jobRuns.runs = append(jobRuns.runs, planned)
+		}
+		due = append(due, jobRuns)
+	}
+	return due
+}
+
+// record stores run in the history and moves its job on. It reports whether the
+// remaining runs of the job have to wait, because the run is retried later or the
+// job was removed meanwhile.
+func (scheduler *Scheduler) record(run ScheduledRun, now time.Time) (bool, error) {
+	scheduler.state.History = append(scheduler.state.History, run)
+	if limit := scheduler.HistoryLimit; limit > 0 && len(scheduler.state.History) > limit {
+		scheduler.state.History = append([]ScheduledRun(nil), scheduler.state.History[len(scheduler.state.History)-limit:]...)
+	}
+	job := scheduler.job(run.JobID)
+	retry := job == nil
+	if job != nil {
+		if run.Status == ScheduledRunFailed && run.Attempt <= scheduler.MaxRetries && !scheduler.journaledFailure(run) {
+			retry = true
+			job.Attempts = run.Attempt
+			job.RetryAt = now.Add(scheduler.RetryBackoff << (run.Attempt - 1))
+		} else {
+			job.Attempts = 0
+			job.RetryAt = time.Time{}
+			job.NextRun = scheduler.schedules[job.ID].Next(run.ScheduledAt)
+		}
+	}
+	return retry, scheduler.flush()
+}
+
+// journaledFailure reports whether the transfer of run was refused or failed on
+// chain. Its key can not be sent again, so the run is not retried.
+func (scheduler *Scheduler) journaledFailure(run ScheduledRun) bool {
+	entry, ok := scheduler.Manager.Journal.Get(run.IdempotencyKey)
+	return ok && entry.Status == JournalStatusFailed
+}
+
+func scheduledRunKey(jobID string, at time.Time) string {
+	return fmt.Sprintf("schedule:%s:%d", jobID, at.Unix())
+}
+
+func (scheduler *Scheduler) execute(job *RecurringTransfer, planned plannedRun, now time.Time) ScheduledRun {
+	run := ScheduledRun{
+		JobID:          job.ID,
+		ScheduledAt:    planned.at,
+		ExecutedAt:     now,
+		Attempt:        planned.attempt,
+		IdempotencyKey: planned.key,
+	}
+	result, err := scheduler.send(job, run.IdempotencyKey)
+	if err != nil {
+		run.Status = ScheduledRunFailed
+		run.Error = err.Error()
+		return run
+	}
+	run.Status = ScheduledRunSucceeded
+	run.Signature = result.Signature().String()
+	return run
+}
+
+func (scheduler *Scheduler) send(job *RecurringTransfer, key string) (*OperationResult, error) {
+	if scheduler.Signer == nil {
+		return nil, errors.New("scheduler signer is not configured")
+	}
+	from, err := scheduler.Signer(job.From)
+	if err != nil {
+		return nil, errors.Errorf("failed to get signer for %s. err: %s", job.From.String(), err.Error())
+	}
+	wm := scheduler.Manager.WithIdempotencyKey(key)
+	if job.IsLamports() {
+		return wm.SendLamportsTransaction(from, []SendLamportsInstructionParams{{
+			From:     from,
+			To:       job.To,
+			Lamports: job.Amount,
+			Memo:     job.Memo,
+		}})
+	}
+	return wm.SendTokensTransaction(from, []SendTokensInstructionParams{{
+		From:   from,
+		To:     job.To,
+		Mint:   job.Mint,
+		Amount: job.Amount,
+		Memo:   job.Memo,
+	}})
+}
+
+func (scheduler *Scheduler) now() time.Time {
+	location := scheduler.Location
+	if location == nil {
+		location = time.UTC
+	}
+	return scheduler.Now().In(location)
+}
+
+func (scheduler *Scheduler) job(id string) *RecurringTransfer {
+	for _, job := range scheduler.state.Jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+func (scheduler *Scheduler) flush() error {
+	data, err := json.MarshalIndent(scheduler.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(scheduler.path, data)
+}
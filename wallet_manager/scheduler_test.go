@@ -0,0 +1,319 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/pkg/errors"
+	"path/filepath"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+	"time"
+)
+
+func TestScheduler_MissedRunPolicies(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	from := solana.NewWallet().PrivateKey
+	signer := func(wallet solana.PublicKey) (solana.PrivateKey, error) {
+		if !wallet.Equals(from.PublicKey()) {
+			return nil, errors.New("unknown wallet")
+		}
+		return from, nil
+	}
+	path := filepath.Join(t.TempDir(), "scheduler.json")
+	scheduler, err := OpenScheduler(path, manager, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saturday, the first activation is on Monday 2022-10-03 09:00.
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	scheduler.Now = func() time.Time { return now }
+
+	policies := []MissedRunPolicy{MissedRunSkip, MissedRunCatchUp, MissedRunOnce}
+	for _, policy := range policies {
+		_, err = scheduler.AddJob(RecurringTransfer{
+			ID:              string(policy),
+			Schedule:        "0 9 * * 1",
+			From:            from.PublicKey(),
+			To:              solana.NewWallet().PublicKey(),
+			Amount:          1000,
+			MissedRunPolicy: policy,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now = time.Date(2022, 10, 17, 9, 0, 30, 0, time.UTC)
+	runs, err := scheduler.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 9 {
+		t.Fatalf("expected 9 runs, got %d", len(runs))
+	}
+	expected := map[string][]ScheduledRunStatus{
+		"skip":     {ScheduledRunSkipped, ScheduledRunSkipped, ScheduledRunSucceeded},
+		"catch_up": {ScheduledRunSucceeded, ScheduledRunSucceeded, ScheduledRunSucceeded},
+		"run_once": {ScheduledRunSkipped, ScheduledRunSucceeded, ScheduledRunSucceeded},
+	}
+	for id, statuses := range expected {
+		history := scheduler.History(id)
+		for idx, status := range statuses {
+			if history[idx].Status != status {
+				t.Fatalf("job %s run %d: expected %s, got %s (%s)", id, idx, status, history[idx].Status, history[idx].Error)
+			}
+		}
+	}
+	if sent := fake.Calls("sendTransaction"); sent != 6 {
+		t.Fatalf("expected 6 transfers, got %d", sent)
+	}
+
+	if runs, err = scheduler.RunDue(); err != nil || len(runs) != 0 {
+		t.Fatalf("expected nothing to be due, got %d runs. err: %v", len(runs), err)
+	}
+
+	reopened, err := OpenScheduler(path, manager, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.History("")) != 9 {
+		t.Fatalf("expected history to be persisted, got %d runs", len(reopened.History("")))
+	}
+	for _, job := range reopened.Jobs() {
+		if !job.NextRun.Equal(time.Date(2022, 10, 24, 9, 0, 0, 0, time.UTC)) {
+			t.Fatalf("unexpected next run of %s: %s", job.ID, job.NextRun)
+		}
+	}
+}
+
+func TestScheduler_FailedRun(t *testing.T) {
+	_, manager := newJournaledFake(t)
+	scheduler, err := OpenScheduler(filepath.Join(t.TempDir(), "scheduler.json"), manager, func(solana.PublicKey) (solana.PrivateKey, error) {
+		return nil, errors.New("locked")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	scheduler.Now = func() time.Time { return now }
+	if _, err = scheduler.AddJob(RecurringTransfer{Schedule: "@hourly", Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	runs, err := scheduler.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Status != ScheduledRunFailed {
+		t.Fatalf("expected a failed run, got %+v", runs)
+	}
+}
+
+func TestScheduler_RetryFailedRun(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	from := solana.NewWallet().PrivateKey
+	locked := true
+	var scheduler *Scheduler
+	signer := func(solana.PublicKey) (solana.PrivateKey, error) {
+		// sends run without the scheduler lock
+		if len(scheduler.Jobs()) != 1 {
+			t.Error("expected the job to be listed during the send")
+		}
+		if locked {
+			return nil, errors.New("locked")
+		}
+		return from, nil
+	}
+	scheduler, err := OpenScheduler(filepath.Join(t.TempDir(), "scheduler.json"), manager, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler.HistoryLimit = 2
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	scheduler.Now = func() time.Time { return now }
+	job, err := scheduler.AddJob(RecurringTransfer{Schedule: "@hourly", From: from.PublicKey(), To: solana.NewWallet().PublicKey(), Amount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Hour)
+	runs, err := scheduler.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Status != ScheduledRunFailed || runs[0].Attempt != 1 {
+		t.Fatalf("expected a failed run, got %+v", runs)
+	}
+	if runs, _ = scheduler.RunDue(); len(runs) != 0 {
+		t.Fatalf("retry should wait for the backoff, got %+v", runs)
+	}
+
+	locked = false
+	now = now.Add(scheduler.RetryBackoff)
+	runs, err = scheduler.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Status != ScheduledRunSucceeded || runs[0].Attempt != 2 {
+		t.Fatalf("expected a successful retry, got %+v", runs)
+	}
+	if !runs[0].ScheduledAt.Equal(time.Date(2022, 10, 1, 13, 0, 0, 0, time.UTC)) || fake.Calls("sendTransaction") != 1 {
+		t.Fatalf("unexpected retried run %+v", runs[0])
+	}
+	jobs := scheduler.Jobs()
+	if jobs[0].Attempts != 0 || !jobs[0].NextRun.Equal(time.Date(2022, 10, 1, 14, 0, 0, 0, time.UTC)) {
+		t.Fatalf("job should move on after the retry, got %+v", jobs[0])
+	}
+
+	now = now.Add(time.Hour)
+	if _, err = scheduler.RunDue(); err != nil {
+		t.Fatal(err)
+	}
+	history := scheduler.History(job.ID)
+	if len(history) != 2 || history[0].Attempt != 2 {
+		t.Fatalf("expected history to keep the 2 latest runs, got %+v", history)
+	}
+}
+
+func TestScheduler_LandedRunWithoutDetails(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	fake.Handle("getTransaction", func(params []interface{}) (interface{}, error) {
+		return nil, errors.New("lookup failed")
+	})
+	from := solana.NewWallet().PrivateKey
+	scheduler, err := OpenScheduler(filepath.Join(t.TempDir(), "scheduler.json"), manager, func(solana.PublicKey) (solana.PrivateKey, error) {
+		return from, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	scheduler.Now = func() time.Time { return now }
+	if _, err = scheduler.AddJob(RecurringTransfer{Schedule: "@hourly", From: from.PublicKey(), To: solana.NewWallet().PublicKey(), Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Hour)
+	runs, err := scheduler.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Status != ScheduledRunSucceeded {
+		t.Fatalf("a landed transfer must count as paid, got %+v", runs)
+	}
+	now = now.Add(scheduler.RetryBackoff)
+	if runs, _ = scheduler.RunDue(); len(runs) != 0 || fake.Calls("sendTransaction") != 1 {
+		t.Fatalf("the transfer must not be paid again, got %+v", runs)
+	}
+}
+
+func TestScheduler_RetryKeepsRunKey(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	manager.ConfirmationTimeout = 50 * time.Millisecond
+	landed := false
+	fake.Handle("getSignatureStatuses", func(params []interface{}) (interface{}, error) {
+		status := "processed"
+		if landed {
+			status = "confirmed"
+		}
+		return fakerpc.Value([]interface{}{
+			map[string]interface{}{"slot": 10, "err": nil, "confirmationStatus": status},
+		}), nil
+	})
+	from := solana.NewWallet().PrivateKey
+	scheduler, err := OpenScheduler(filepath.Join(t.TempDir(), "scheduler.json"), manager, func(solana.PublicKey) (solana.PrivateKey, error) {
+		return from, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	scheduler.Now = func() time.Time { return now }
+	if _, err = scheduler.AddJob(RecurringTransfer{Schedule: "@hourly", From: from.PublicKey(), To: solana.NewWallet().PublicKey(), Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Hour)
+	first, err := scheduler.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || first[0].Status != ScheduledRunFailed {
+		t.Fatalf("expected the unconfirmed run to fail, got %+v", first)
+	}
+	landed = true
+	now = now.Add(scheduler.RetryBackoff)
+	retried, err := scheduler.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(retried) != 1 || retried[0].Status != ScheduledRunSucceeded || retried[0].IdempotencyKey != first[0].IdempotencyKey {
+		t.Fatalf("expected the retry to resolve the first attempt under its key, got %+v", retried)
+	}
+	if fake.Calls("sendTransaction") != 1 {
+		t.Fatalf("the transfer must be sent once, got %d sends", fake.Calls("sendTransaction"))
+	}
+}
+
+func TestScheduler_NoRetryAfterJournaledFailure(t *testing.T) {
+	fake, manager := newJournaledFake(t)
+	fake.Handle("sendTransaction", func(params []interface{}) (interface{}, error) {
+		return nil, &jsonrpc.RPCError{Code: -32002, Message: "Transaction simulation failed"}
+	})
+	from := solana.NewWallet().PrivateKey
+	scheduler, err := OpenScheduler(filepath.Join(t.TempDir(), "scheduler.json"), manager, func(solana.PublicKey) (solana.PrivateKey, error) {
+		return from, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	scheduler.Now = func() time.Time { return now }
+	if _, err = scheduler.AddJob(RecurringTransfer{Schedule: "@hourly", From: from.PublicKey(), To: solana.NewWallet().PublicKey(), Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Hour)
+	runs, err := scheduler.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Status != ScheduledRunFailed {
+		t.Fatalf("expected a failed run, got %+v", runs)
+	}
+	jobs := scheduler.Jobs()
+	if jobs[0].Attempts != 0 || !jobs[0].NextRun.Equal(time.Date(2022, 10, 1, 14, 0, 0, 0, time.UTC)) {
+		t.Fatalf("a run whose transfer failed must not be retried, got %+v", jobs[0])
+	}
+}
+
+func TestScheduler_RetriesExhausted(t *testing.T) {
+	_, manager := newJournaledFake(t)
+	scheduler, err := OpenScheduler(filepath.Join(t.TempDir(), "scheduler.json"), manager, func(solana.PublicKey) (solana.PrivateKey, error) {
+		return nil, errors.New("locked")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler.MaxRetries = 1
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	scheduler.Now = func() time.Time { return now }
+	if _, err = scheduler.AddJob(RecurringTransfer{Schedule: "@hourly", Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	for attempt := 1; attempt <= 2; attempt++ {
+		runs, err := scheduler.RunDue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != 1 || runs[0].Attempt != attempt {
+			t.Fatalf("expected attempt %d, got %+v", attempt, runs)
+		}
+		now = now.Add(scheduler.RetryBackoff)
+	}
+	jobs := scheduler.Jobs()
+	if jobs[0].Attempts != 0 || !jobs[0].NextRun.Equal(time.Date(2022, 10, 1, 14, 0, 0, 0, time.UTC)) {
+		t.Fatalf("job should move on once retries are exhausted, got %+v", jobs[0])
+	}
+}
@@ -0,0 +1,58 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+)
+
+// PartialSignTransaction adds signatures of the given signers to tx and leaves
+// slots of signers that are not available empty, so the transaction can be
+// handed over to co-signers.
+func PartialSignTransaction(tx *solana.Transaction, signers []solana.PrivateKey) error {
+	payload, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	requiredSignatures := int(tx.Message.Header.NumRequiredSignatures)
+	if len(tx.Signatures) == 0 {
+		tx.Signatures = make([]solana.Signature, requiredSignatures)
+	}
+	if len(tx.Signatures) != requiredSignatures {
+		return errors.Errorf("transaction has %d signatures, %d expected", len(tx.Signatures), requiredSignatures)
+	}
+	for idx, key := range tx.Message.AccountKeys[:requiredSignatures] {
+		for _, signer := range signers {
+			if !signer.PublicKey().Equals(key) {
+				continue
+			}
+			sig, err := signer.Sign(payload)
+			if err != nil {
+				return errors.Errorf("failed to sign with %s. err: %s", key.String(), err.Error())
+			}
+			tx.Signatures[idx] = sig
+		}
+	}
+	return nil
+}
+
+// SignTransaction signs tx with signers and fails if any required signature is still missing.
+func SignTransaction(tx *solana.Transaction, signers []solana.PrivateKey) error {
+	if err := PartialSignTransaction(tx, signers); err != nil {
+		return err
+	}
+	missing := MissingSigners(tx)
+	if len(missing) > 0 {
+		return errors.Errorf("signer key %s not found", missing[0].String())
+	}
+	return nil
+}
+
+func MissingSigners(tx *solana.Transaction) []solana.PublicKey {
+	var missing []solana.PublicKey
+	for idx, key := range tx.Message.Signers() {
+		if idx >= len(tx.Signatures) || tx.Signatures[idx].IsZero() {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
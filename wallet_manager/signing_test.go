@@ -0,0 +1,45 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"testing"
+)
+
+func TestPartialSignTransaction(t *testing.T) {
+	feePayer := solana.NewWallet().PrivateKey
+	cosignerA := solana.NewWallet().PrivateKey
+	cosignerB := solana.NewWallet().PrivateKey
+	multisig := solana.NewWallet().PublicKey()
+	instruction := token.NewTransferInstructionBuilder().
+		SetAmount(1).
+		SetSourceAccount(solana.NewWallet().PublicKey()).
+		SetDestinationAccount(solana.NewWallet().PublicKey()).
+		SetOwnerAccount(multisig, cosignerA.PublicKey(), cosignerB.PublicKey()).
+		Build()
+	tx, err := solana.NewTransactionBuilder().
+		SetRecentBlockHash(solana.Hash{}).
+		SetFeePayer(feePayer.PublicKey()).
+		AddInstruction(instruction).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = PartialSignTransaction(tx, []solana.PrivateKey{feePayer, cosignerA}); err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Signatures) != 3 {
+		t.Fatalf("expected 3 signature slots, got %d", len(tx.Signatures))
+	}
+	if err = tx.VerifySignatures(); err == nil {
+		t.Fatal("transaction should not verify without the second co-signer")
+	}
+
+	if err = PartialSignTransaction(tx, []solana.PrivateKey{cosignerB}); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.VerifySignatures(); err != nil {
+		t.Fatal(err)
+	}
+}
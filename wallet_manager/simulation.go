@@ -0,0 +1,203 @@
+package wallet_manager
+
+import (
+	"encoding/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"sync"
+)
+
+const tokenAccountSize = 165
+
+type LamportsChange struct {
+	Account solana.PublicKey
+	Pre     uint64
+	Post    uint64
+}
+
+func (change LamportsChange) Delta() int64 {
+	return int64(change.Post) - int64(change.Pre)
+}
+
+type TokenBalanceChange struct {
+	Account solana.PublicKey
+	Mint    solana.PublicKey
+	Owner   solana.PublicKey
+	Pre     uint64
+	Post    uint64
+}
+
+func (change TokenBalanceChange) Delta() int64 {
+	return int64(change.Post) - int64(change.Pre)
+}
+
+type SimulationResult struct {
+	Signature       solana.Signature
+	Err             interface{}
+	Logs            []string
+	UnitsConsumed   uint64
+	Fee             uint64
+	LamportsChanges []LamportsChange
+	TokenChanges    []TokenBalanceChange
+}
+
+type simulationRecorder struct {
+	mu          sync.Mutex
+	simulations []*SimulationResult
+}
+
+// DryRun returns a copy of wm which simulates transactions instead of sending them.
+// Operations called on the copy return a simulated OperationResult; the full
+// simulation results are available through Simulations.
+func (wm *WalletManager) DryRun() *WalletManager {
+	dryRun := *wm
+	dryRun.simulations = &simulationRecorder{}
+	return &dryRun
+}
+
+func (wm *WalletManager) IsDryRun() bool {
+	return wm.simulations != nil
+}
+
+func (wm *WalletManager) Simulations() []*SimulationResult {
+	if wm.simulations == nil {
+		return nil
+	}
+	wm.simulations.mu.Lock()
+	defer wm.simulations.mu.Unlock()
+	return append([]*SimulationResult(nil), wm.simulations.simulations...)
+}
+
+func (wm *WalletManager) SimulateInstructions(
+	feePayer solana.PublicKey,
+	instructions []solana.Instruction,
+	signers []solana.PrivateKey,
+) (*SimulationResult, error) {
+	tx, err := wm.BuildTransaction(feePayer, instructions)
+	if err != nil {
+		return nil, err
+	}
+	if err = PartialSignTransaction(tx, signers); err != nil {
+		return nil, err
+	}
+	return wm.SimulateTransaction(tx)
+}
+
+func (wm *WalletManager) SimulateTransaction(tx *solana.Transaction) (*SimulationResult, error) {
+	accounts := tx.Message.AccountKeys
+	preAccounts, err := wm.Client.GetMultipleAccountsWithOpts(wm.Context, accounts, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: wm.Commitment,
+	})
+	if err != nil {
+		return nil, errors.Errorf("failed to get accounts before simulation. err: %s", err.Error())
+	}
+	simulation, err := wm.Client.SimulateTransactionWithOpts(wm.Context, tx, &rpc.SimulateTransactionOpts{
+		Commitment: wm.Commitment,
+		Accounts: &rpc.SimulateTransactionAccountsOpts{
+			Encoding:  solana.EncodingBase64,
+			Addresses: accounts,
+		},
+	})
+	if err != nil {
+		return nil, errors.Errorf("failed to simulate transaction. err: %s", err.Error())
+	}
+	fee, err := wm.Client.GetFeeForMessage(wm.Context, tx.Message.ToBase64(), wm.Commitment)
+	if err != nil {
+		return nil, errors.Errorf("failed to get fee for transaction. err: %s", err.Error())
+	}
+	result := &SimulationResult{
+		Err:  simulation.Value.Err,
+		Logs: simulation.Value.Logs,
+	}
+	if len(tx.Signatures) > 0 {
+		result.Signature = tx.Signatures[0]
+	}
+	if simulation.Value.UnitsConsumed != nil {
+		result.UnitsConsumed = *simulation.Value.UnitsConsumed
+	}
+	if fee.Value != nil {
+		result.Fee = *fee.Value
+	}
+	if len(simulation.Value.Accounts) == len(accounts) {
+		result.LamportsChanges, result.TokenChanges = diffAccounts(accounts, preAccounts.Value, simulation.Value.Accounts)
+	}
+	return result, nil
+}
+
+func (wm *WalletManager) dryRunTransaction(tx *solana.Transaction) (*OperationResult, error) {
+	result, err := wm.SimulateTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	wm.simulations.mu.Lock()
+	wm.simulations.simulations = append(wm.simulations.simulations, result)
+	wm.simulations.mu.Unlock()
+	if result.Err != nil {
+		return simulationOperationResult(result), errors.Errorf("simulation failed: %v", result.Err)
+	}
+	return simulationOperationResult(result), nil
+}
+
+func diffAccounts(
+	keys []solana.PublicKey,
+	pre []*rpc.Account,
+	post []*rpc.Account,
+) ([]LamportsChange, []TokenBalanceChange) {
+	var lamportsChanges []LamportsChange
+	var tokenChanges []TokenBalanceChange
+	for idx, key := range keys {
+		var preAccount, postAccount *rpc.Account
+		if idx < len(pre) {
+			preAccount = pre[idx]
+		}
+		if idx < len(post) {
+			postAccount = post[idx]
+		}
+		lamportsChange := LamportsChange{Account: key}
+		if preAccount != nil {
+			lamportsChange.Pre = preAccount.Lamports
+		}
+		if postAccount != nil {
+			lamportsChange.Post = postAccount.Lamports
+		}
+		lamportsChanges = append(lamportsChanges, lamportsChange)
+
+		preToken, preOk := decodeTokenAccount(preAccount)
+		postToken, postOk := decodeTokenAccount(postAccount)
+		if !preOk && !postOk {
+			continue
+		}
+		tokenChange := TokenBalanceChange{Account: key}
+		if preOk {
+			tokenChange.Mint, tokenChange.Owner, tokenChange.Pre = preToken.Mint, preToken.Owner, preToken.Amount
+		}
+		if postOk {
+			tokenChange.Mint, tokenChange.Owner, tokenChange.Post = postToken.Mint, postToken.Owner, postToken.Amount
+		}
+		tokenChanges = append(tokenChanges, tokenChange)
+	}
+	return lamportsChanges, tokenChanges
+}
+
+type tokenAccountBalance struct {
+	Mint   solana.PublicKey
+	Owner  solana.PublicKey
+	Amount uint64
+}
+
+func decodeTokenAccount(account *rpc.Account) (tokenAccountBalance, bool) {
+	if account == nil || !account.Owner.Equals(solana.TokenProgramID) || account.Data == nil {
+		return tokenAccountBalance{}, false
+	}
+	data := account.Data.GetBinary()
+	if len(data) != tokenAccountSize {
+		return tokenAccountBalance{}, false
+	}
+	return tokenAccountBalance{
+		Mint:   solana.PublicKeyFromBytes(data[0:32]),
+		Owner:  solana.PublicKeyFromBytes(data[32:64]),
+		Amount: binary.LittleEndian.Uint64(data[64:72]),
+	}, true
+}
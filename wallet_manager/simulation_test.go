@@ -0,0 +1,55 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+)
+
+func TestWalletManager_DryRun(t *testing.T) {
+	from := solana.NewWallet().PrivateKey
+	to := solana.NewWallet().PublicKey()
+	fake := fakerpc.New()
+	fake.Handle("getMultipleAccounts", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value([]interface{}{
+			fakerpc.Account(10000000, solana.SystemProgramID, nil),
+			nil,
+			fakerpc.Account(1, solana.BPFLoaderProgramID, nil),
+		}), nil
+	})
+	fake.Handle("simulateTransaction", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value(map[string]interface{}{
+			"err":           nil,
+			"logs":          []string{"Program 11111111111111111111111111111111 success"},
+			"unitsConsumed": 150,
+			"accounts": []interface{}{
+				fakerpc.Account(10000000-5000-1000000, solana.SystemProgramID, nil),
+				fakerpc.Account(1000000, solana.SystemProgramID, nil),
+				fakerpc.Account(1, solana.BPFLoaderProgramID, nil),
+			},
+		}), nil
+	})
+	dryRun := newFakeWalletManager(fake).DryRun()
+
+	operation, err := dryRun.SendLamports(from, to, 1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.Calls("sendTransaction") != 0 {
+		t.Fatal("dry run must not send transactions")
+	}
+	simulations := dryRun.Simulations()
+	if len(simulations) != 1 {
+		t.Fatalf("expected 1 simulation, got %d", len(simulations))
+	}
+	result := simulations[0]
+	if !operation.Simulated || operation.Fee != 5000 {
+		t.Fatalf("unexpected operation result %+v", operation)
+	}
+	if result.Signature != operation.Signature() || result.Fee != 5000 || result.UnitsConsumed != 150 || len(result.Logs) != 1 {
+		t.Fatalf("unexpected simulation result %+v", result)
+	}
+	if result.LamportsChanges[0].Delta() != -1005000 || result.LamportsChanges[1].Delta() != 1000000 {
+		t.Fatalf("unexpected balance changes %+v", result.LamportsChanges)
+	}
+}
@@ -0,0 +1,270 @@
+package wallet_manager
+
+import (
+	"encoding/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+)
+
+type StakeState uint32
+
+const (
+	StakeStateUninitialized StakeState = iota
+	StakeStateInitialized
+	StakeStateDelegated
+	StakeStateRewardsPool
+)
+
+const stakeStakerOffset = 12
+
+type StakeAccount struct {
+	Address           solana.PublicKey
+	Lamports          uint64
+	State             StakeState
+	RentExemptReserve uint64
+	Staker            solana.PublicKey
+	Withdrawer        solana.PublicKey
+	Voter             solana.PublicKey
+	DelegatedStake    uint64
+	ActivationEpoch   uint64
+	DeactivationEpoch uint64
+	Activation        rpc.ActivationStateType
+	ActiveStake       uint64
+	InactiveStake     uint64
+}
+
+func (wm *WalletManager) CreateStakeAccount(
+	payer solana.PrivateKey,
+	authority solana.PublicKey,
+	lamports uint64,
+) (solana.PublicKey, *OperationResult, error) {
+	if err := wm.checkStakeRentExemption(lamports); err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	stake := solana.NewWallet().PrivateKey
+	createInstruction := system.NewCreateAccountInstructionBuilder().
+		SetLamports(lamports).
+		SetSpace(StakeAccountSize).
+		SetOwner(StakeProgramID).
+		SetFundingAccount(payer.PublicKey()).
+		SetNewAccount(stake.PublicKey()).
+		Build()
+	result, err := wm.SendAndConfirmInstructions(
+		payer.PublicKey(),
+		[]solana.Instruction{
+			createInstruction,
+			NewStakeInitializeInstruction(stake.PublicKey(), authority, authority),
+		},
+		[]solana.PrivateKey{payer, stake},
+	)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	return stake.PublicKey(), result, nil
+}
+
+func (wm *WalletManager) CreateStakeAccountWithSeed(
+	payer solana.PrivateKey,
+	base solana.PrivateKey,
+	seed string,
+	authority solana.PublicKey,
+	lamports uint64,
+) (solana.PublicKey, *OperationResult, error) {
+	if err := wm.checkStakeRentExemption(lamports); err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	stake, err := solana.CreateWithSeed(base.PublicKey(), seed, StakeProgramID)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	createInstruction := system.NewCreateAccountWithSeedInstructionBuilder().
+		SetBase(base.PublicKey()).
+		SetSeed(seed).
+		SetLamports(lamports).
+		SetSpace(StakeAccountSize).
+		SetOwner(StakeProgramID).
+		SetFundingAccount(payer.PublicKey()).
+		SetCreatedAccount(stake).
+		SetBaseAccount(base.PublicKey()).
+		Build()
+	result, err := wm.SendAndConfirmInstructions(
+		payer.PublicKey(),
+		[]solana.Instruction{
+			createInstruction,
+			NewStakeInitializeInstruction(stake, authority, authority),
+		},
+		appendSignerIfNotPresented([]solana.PrivateKey{payer}, base),
+	)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	return stake, result, nil
+}
+
+func (wm *WalletManager) DelegateStake(authority solana.PrivateKey, stake, vote solana.PublicKey) (*OperationResult, error) {
+	return wm.SendAndConfirmInstructions(
+		authority.PublicKey(),
+		[]solana.Instruction{NewStakeDelegateInstruction(stake, vote, authority.PublicKey())},
+		[]solana.PrivateKey{authority},
+	)
+}
+
+func (wm *WalletManager) DeactivateStake(authority solana.PrivateKey, stake solana.PublicKey) (*OperationResult, error) {
+	return wm.SendAndConfirmInstructions(
+		authority.PublicKey(),
+		[]solana.Instruction{NewStakeDeactivateInstruction(stake, authority.PublicKey())},
+		[]solana.PrivateKey{authority},
+	)
+}
+
+func (wm *WalletManager) WithdrawStake(
+	authority solana.PrivateKey,
+	stake,
+	to solana.PublicKey,
+	lamports uint64,
+) (*OperationResult, error) {
+	return wm.SendAndConfirmInstructions(
+		authority.PublicKey(),
+		[]solana.Instruction{NewStakeWithdrawInstruction(stake, to, authority.PublicKey(), lamports)},
+		[]solana.PrivateKey{authority},
+	)
+}
+
+func (wm *WalletManager) SplitStake(
+	authority solana.PrivateKey,
+	stake solana.PublicKey,
+	lamports uint64,
+) (solana.PublicKey, *OperationResult, error) {
+	// the split stake account has to be rent exempt before the stake program moves lamports into it
+	rent, err := wm.Client.GetMinimumBalanceForRentExemption(wm.Context, StakeAccountSize, wm.Commitment)
+	if err != nil {
+		return solana.PublicKey{}, nil, errors.Errorf("failed to get rent exemption. err: %s", err.Error())
+	}
+	splitStake := solana.NewWallet().PrivateKey
+	allocateInstruction := system.NewAllocateInstructionBuilder().
+		SetSpace(StakeAccountSize).
+		SetNewAccount(splitStake.PublicKey()).
+		Build()
+	assignInstruction := system.NewAssignInstructionBuilder().
+		SetOwner(StakeProgramID).
+		SetAssignedAccount(splitStake.PublicKey()).
+		Build()
+	result, err := wm.SendAndConfirmInstructions(
+		authority.PublicKey(),
+		[]solana.Instruction{
+			makeTransferInstruction(authority.PublicKey(), splitStake.PublicKey(), rent),
+			allocateInstruction,
+			assignInstruction,
+			NewStakeSplitInstruction(stake, splitStake.PublicKey(), authority.PublicKey(), lamports),
+		},
+		[]solana.PrivateKey{authority, splitStake},
+	)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	return splitStake.PublicKey(), result, nil
+}
+
+func (wm *WalletManager) MergeStake(authority solana.PrivateKey, destination, source solana.PublicKey) (*OperationResult, error) {
+	return wm.SendAndConfirmInstructions(
+		authority.PublicKey(),
+		[]solana.Instruction{NewStakeMergeInstruction(destination, source, authority.PublicKey())},
+		[]solana.PrivateKey{authority},
+	)
+}
+
+func (wm *WalletManager) GetStakeAccount(stake solana.PublicKey) (StakeAccount, error) {
+	info, err := wm.Client.GetAccountInfoWithOpts(wm.Context, stake, &rpc.GetAccountInfoOpts{
+		Commitment: wm.Commitment,
+	})
+	if err != nil {
+		return StakeAccount{}, errors.Errorf("failed to get stake account %s. err: %s", stake.String(), err.Error())
+	}
+	return wm.makeStakeAccount(stake, info.Value)
+}
+
+// GetStakeAccounts returns every stake account whose stake authority is authority.
+func (wm *WalletManager) GetStakeAccounts(authority solana.PublicKey) ([]StakeAccount, error) {
+	accounts, err := wm.Client.GetProgramAccountsWithOpts(wm.Context, StakeProgramID, &rpc.GetProgramAccountsOpts{
+		Commitment: wm.Commitment,
+		Encoding:   solana.EncodingBase64,
+		Filters: []rpc.RPCFilter{
+			{DataSize: StakeAccountSize},
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: stakeStakerOffset, Bytes: authority.Bytes()}},
+		},
+	})
+	if err != nil {
+		return nil, errors.Errorf("failed to get stake accounts of %s. err: %s", authority.String(), err.Error())
+	}
+	var stakeAccounts []StakeAccount
+	for _, account := range accounts {
+		stakeAccount, err := wm.makeStakeAccount(account.Pubkey, account.Account)
+		if err != nil {
+			return nil, err
+		}
+		stakeAccounts = append(stakeAccounts, stakeAccount)
+	}
+	return stakeAccounts, nil
+}
+
+func (wm *WalletManager) makeStakeAccount(address solana.PublicKey, account *rpc.Account) (StakeAccount, error) {
+	if account == nil {
+		return StakeAccount{}, errors.Errorf("stake account %s not found", address.String())
+	}
+	if !account.Owner.Equals(StakeProgramID) {
+		return StakeAccount{}, errors.Errorf("account %s is not owned by the stake program", address.String())
+	}
+	stakeAccount, err := decodeStakeAccount(account.Data.GetBinary())
+	if err != nil {
+		return StakeAccount{}, errors.Errorf("failed to decode stake account %s. err: %s", address.String(), err.Error())
+	}
+	stakeAccount.Address = address
+	stakeAccount.Lamports = account.Lamports
+	stakeAccount.Activation = rpc.ActivationStateInactive
+	if stakeAccount.State == StakeStateDelegated {
+		activation, err := wm.Client.GetStakeActivation(wm.Context, address, wm.Commitment, nil)
+		if err != nil {
+			return StakeAccount{}, errors.Errorf("failed to get activation of %s. err: %s", address.String(), err.Error())
+		}
+		stakeAccount.Activation = activation.State
+		stakeAccount.ActiveStake = activation.Active
+		stakeAccount.InactiveStake = activation.Inactive
+	}
+	return stakeAccount, nil
+}
+
+func decodeStakeAccount(data []byte) (StakeAccount, error) {
+	if len(data) < 4 {
+		return StakeAccount{}, errors.New("stake account data is too short")
+	}
+	stakeAccount := StakeAccount{State: StakeState(binary.LittleEndian.Uint32(data))}
+	if stakeAccount.State != StakeStateInitialized && stakeAccount.State != StakeStateDelegated {
+		return stakeAccount, nil
+	}
+	if len(data) < StakeAccountSize {
+		return StakeAccount{}, errors.Errorf("stake account data has %d bytes, %d expected", len(data), StakeAccountSize)
+	}
+	stakeAccount.RentExemptReserve = binary.LittleEndian.Uint64(data[4:])
+	stakeAccount.Staker = solana.PublicKeyFromBytes(data[12:44])
+	stakeAccount.Withdrawer = solana.PublicKeyFromBytes(data[44:76])
+	if stakeAccount.State == StakeStateDelegated {
+		stakeAccount.Voter = solana.PublicKeyFromBytes(data[124:156])
+		stakeAccount.DelegatedStake = binary.LittleEndian.Uint64(data[156:])
+		stakeAccount.ActivationEpoch = binary.LittleEndian.Uint64(data[164:])
+		stakeAccount.DeactivationEpoch = binary.LittleEndian.Uint64(data[172:])
+	}
+	return stakeAccount, nil
+}
+
+func (wm *WalletManager) checkStakeRentExemption(lamports uint64) error {
+	rent, err := wm.Client.GetMinimumBalanceForRentExemption(wm.Context, StakeAccountSize, wm.Commitment)
+	if err != nil {
+		return errors.Errorf("failed to get rent exemption. err: %s", err.Error())
+	}
+	if lamports < rent {
+		return errors.Errorf("stake account needs at least %d lamports, got %d", rent, lamports)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package wallet_manager
+
+import (
+	"encoding/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+const StakeAccountSize = 200
+
+var (
+	StakeProgramID = solana.MustPublicKeyFromBase58("Stake11111111111111111111111111111111111111")
+	StakeConfigID  = solana.MustPublicKeyFromBase58("StakeConfig11111111111111111111111111111111")
+)
+
+const (
+	stakeInstructionInitialize uint32 = iota
+	stakeInstructionAuthorize
+	stakeInstructionDelegateStake
+	stakeInstructionSplit
+	stakeInstructionWithdraw
+	stakeInstructionDeactivate
+	stakeInstructionSetLockup
+	stakeInstructionMerge
+)
+
+func NewStakeInitializeInstruction(stake, staker, withdrawer solana.PublicKey) solana.Instruction {
+	data := stakeInstructionData(stakeInstructionInitialize)
+	data = append(data, staker.Bytes()...)
+	data = append(data, withdrawer.Bytes()...)
+	// empty lockup: unix timestamp, epoch and custodian
+	data = append(data, make([]byte, 8+8+solana.PublicKeyLength)...)
+	return solana.NewInstruction(
+		StakeProgramID,
+		solana.AccountMetaSlice{
+			solana.NewAccountMeta(stake, true, false),
+			solana.NewAccountMeta(solana.SysVarRentPubkey, false, false),
+		},
+		data,
+	)
+}
+
+func NewStakeDelegateInstruction(stake, vote, authority solana.PublicKey) solana.Instruction {
+	return solana.NewInstruction(
+		StakeProgramID,
+		solana.AccountMetaSlice{
+			solana.NewAccountMeta(stake, true, false),
+			solana.NewAccountMeta(vote, false, false),
+			solana.NewAccountMeta(solana.SysVarClockPubkey, false, false),
+			solana.NewAccountMeta(solana.SysVarStakeHistoryPubkey, false, false),
+			solana.NewAccountMeta(StakeConfigID, false, false),
+			solana.NewAccountMeta(authority, false, true),
+		},
+		stakeInstructionData(stakeInstructionDelegateStake),
+	)
+}
+
+func NewStakeSplitInstruction(stake, splitStake, authority solana.PublicKey, lamports uint64) solana.Instruction {
+	return solana.NewInstruction(
+		StakeProgramID,
+		solana.AccountMetaSlice{
+			solana.NewAccountMeta(stake, true, false),
+			solana.NewAccountMeta(splitStake, true, false),
+			solana.NewAccountMeta(authority, false, true),
+		},
+		appendUint64(stakeInstructionData(stakeInstructionSplit), lamports),
+	)
+}
+
+func NewStakeWithdrawInstruction(stake, recipient, authority solana.PublicKey, lamports uint64) solana.Instruction {
+	return solana.NewInstruction(
+		StakeProgramID,
+		solana.AccountMetaSlice{
+			solana.NewAccountMeta(stake, true, false),
+			solana.NewAccountMeta(recipient, true, false),
+			solana.NewAccountMeta(solana.SysVarClockPubkey, false, false),
+			solana.NewAccountMeta(solana.SysVarStakeHistoryPubkey, false, false),
+			solana.NewAccountMeta(authority, false, true),
+		},
+		appendUint64(stakeInstructionData(stakeInstructionWithdraw), lamports),
+	)
+}
+
+func NewStakeDeactivateInstruction(stake, authority solana.PublicKey) solana.Instruction {
+	return solana.NewInstruction(
+		StakeProgramID,
+		solana.AccountMetaSlice{
+			solana.NewAccountMeta(stake, true, false),
+			solana.NewAccountMeta(solana.SysVarClockPubkey, false, false),
+			solana.NewAccountMeta(authority, false, true),
+		},
+		stakeInstructionData(stakeInstructionDeactivate),
+	)
+}
+
+func NewStakeMergeInstruction(destination, source, authority solana.PublicKey) solana.Instruction {
+	return solana.NewInstruction(
+		StakeProgramID,
+		solana.AccountMetaSlice{
+			solana.NewAccountMeta(destination, true, false),
+			solana.NewAccountMeta(source, true, false),
+			solana.NewAccountMeta(solana.SysVarClockPubkey, false, false),
+			solana.NewAccountMeta(solana.SysVarStakeHistoryPubkey, false, false),
+			solana.NewAccountMeta(authority, false, true),
+		},
+		stakeInstructionData(stakeInstructionMerge),
+	)
+}
+
+func stakeInstructionData(instruction uint32) []byte {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, instruction)
+	return data
+}
+
+func appendUint64(data []byte, value uint64) []byte {
+	valueBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(valueBytes, value)
+	return append(data, valueBytes...)
+}
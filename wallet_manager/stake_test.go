@@ -0,0 +1,172 @@
+package wallet_manager
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+)
+
+func TestDecodeStakeAccount(t *testing.T) {
+	staker := solana.NewWallet().PublicKey()
+	withdrawer := solana.NewWallet().PublicKey()
+	voter := solana.NewWallet().PublicKey()
+	data := make([]byte, StakeAccountSize)
+	binary.LittleEndian.PutUint32(data, uint32(StakeStateDelegated))
+	binary.LittleEndian.PutUint64(data[4:], 2282880)
+	copy(data[12:], staker.Bytes())
+	copy(data[44:], withdrawer.Bytes())
+	copy(data[124:], voter.Bytes())
+	binary.LittleEndian.PutUint64(data[156:], 1000000000)
+	binary.LittleEndian.PutUint64(data[164:], 300)
+	binary.LittleEndian.PutUint64(data[172:], ^uint64(0))
+
+	stakeAccount, err := decodeStakeAccount(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stakeAccount.State != StakeStateDelegated ||
+		stakeAccount.RentExemptReserve != 2282880 ||
+		!stakeAccount.Staker.Equals(staker) ||
+		!stakeAccount.Withdrawer.Equals(withdrawer) ||
+		!stakeAccount.Voter.Equals(voter) ||
+		stakeAccount.DelegatedStake != 1000000000 ||
+		stakeAccount.ActivationEpoch != 300 ||
+		stakeAccount.DeactivationEpoch != ^uint64(0) {
+		t.Fatalf("unexpected stake account %+v", stakeAccount)
+	}
+}
+
+func TestStakeInstructions(t *testing.T) {
+	stake := solana.NewWallet().PublicKey()
+	authority := solana.NewWallet().PublicKey()
+
+	initialize := NewStakeInitializeInstruction(stake, authority, authority)
+	data, _ := initialize.Data()
+	if len(data) != 4+32+32+8+8+32 {
+		t.Fatalf("unexpected initialize data length %d", len(data))
+	}
+
+	split := NewStakeSplitInstruction(stake, solana.NewWallet().PublicKey(), authority, 42)
+	data, _ = split.Data()
+	if binary.LittleEndian.Uint32(data) != stakeInstructionSplit || binary.LittleEndian.Uint64(data[4:]) != 42 {
+		t.Fatalf("unexpected split data %v", data)
+	}
+	accounts := split.Accounts()
+	if !accounts[2].PublicKey.Equals(authority) || !accounts[2].IsSigner {
+		t.Fatal("stake authority must sign split")
+	}
+}
+
+func stakeInstructionTypes(t *testing.T, tx *solana.Transaction) []string {
+	var types []string
+	for _, instruction := range tx.Message.Instructions {
+		program, err := tx.Message.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case program.Equals(solana.SystemProgramID):
+			types = append(types, fmt.Sprintf("system:%d", binary.LittleEndian.Uint32(instruction.Data)))
+		case program.Equals(StakeProgramID):
+			types = append(types, fmt.Sprintf("stake:%d", binary.LittleEndian.Uint32(instruction.Data)))
+		default:
+			types = append(types, program.String())
+		}
+	}
+	return types
+}
+
+func TestWalletManager_StakeLifecycle(t *testing.T) {
+	fake := fakerpc.New()
+	manager := newFakeWalletManager(fake)
+	authority := solana.NewWallet().PrivateKey
+	vote := solana.NewWallet().PublicKey()
+	rent := uint64((StakeAccountSize + 128) * 6960)
+
+	if _, _, err := manager.CreateStakeAccount(authority, authority.PublicKey(), rent-1); err == nil {
+		t.Fatal("expected a stake account below rent exemption to be rejected")
+	}
+	stake, _, err := manager.CreateStakeAccount(authority, authority.PublicKey(), 2*solana.LAMPORTS_PER_SOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := stakeInstructionTypes(t, fake.LastSent()); fmt.Sprint(types) != fmt.Sprintf("[system:%d stake:%d]", system.Instruction_CreateAccount, stakeInstructionInitialize) {
+		t.Fatalf("unexpected create instructions %v", types)
+	}
+	data := make([]byte, StakeAccountSize)
+	binary.LittleEndian.PutUint32(data, uint32(StakeStateInitialized))
+	binary.LittleEndian.PutUint64(data[4:], rent)
+	copy(data[12:], authority.PublicKey().Bytes())
+	copy(data[44:], authority.PublicKey().Bytes())
+	fake.SetAccount(stake, StakeProgramID, 2*solana.LAMPORTS_PER_SOL, data)
+	accounts, err := manager.GetStakeAccounts(authority.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || !accounts[0].Address.Equals(stake) || accounts[0].State != StakeStateInitialized {
+		t.Fatalf("unexpected stake accounts %+v", accounts)
+	}
+
+	if _, err = manager.DelegateStake(authority, stake, vote); err != nil {
+		t.Fatal(err)
+	}
+	if types := stakeInstructionTypes(t, fake.LastSent()); fmt.Sprint(types) != fmt.Sprintf("[stake:%d]", stakeInstructionDelegateStake) {
+		t.Fatalf("unexpected delegate instructions %v", types)
+	}
+	binary.LittleEndian.PutUint32(data, uint32(StakeStateDelegated))
+	copy(data[124:], vote.Bytes())
+	binary.LittleEndian.PutUint64(data[156:], 2*solana.LAMPORTS_PER_SOL-rent)
+	fake.SetAccount(stake, StakeProgramID, 2*solana.LAMPORTS_PER_SOL, data)
+	fake.Handle("getStakeActivation", func(params []interface{}) (interface{}, error) {
+		return map[string]interface{}{"state": "active", "active": 2*solana.LAMPORTS_PER_SOL - rent, "inactive": 0}, nil
+	})
+	account, err := manager.GetStakeAccount(stake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !account.Voter.Equals(vote) || account.Activation != rpc.ActivationStateActive || account.ActiveStake != 2*solana.LAMPORTS_PER_SOL-rent {
+		t.Fatalf("unexpected delegated stake account %+v", account)
+	}
+
+	if _, err = manager.DeactivateStake(authority, stake); err != nil {
+		t.Fatal(err)
+	}
+	if types := stakeInstructionTypes(t, fake.LastSent()); fmt.Sprint(types) != fmt.Sprintf("[stake:%d]", stakeInstructionDeactivate) {
+		t.Fatalf("unexpected deactivate instructions %v", types)
+	}
+
+	if _, err = manager.WithdrawStake(authority, stake, authority.PublicKey(), solana.LAMPORTS_PER_SOL); err != nil {
+		t.Fatal(err)
+	}
+	withdraw := fake.LastSent()
+	if types := stakeInstructionTypes(t, withdraw); fmt.Sprint(types) != fmt.Sprintf("[stake:%d]", stakeInstructionWithdraw) {
+		t.Fatalf("unexpected withdraw instructions %v", types)
+	}
+	if binary.LittleEndian.Uint64(withdraw.Message.Instructions[0].Data[4:]) != solana.LAMPORTS_PER_SOL {
+		t.Fatal("unexpected withdraw amount")
+	}
+
+	splitStake, _, err := manager.SplitStake(authority, stake, solana.LAMPORTS_PER_SOL/2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	split := fake.LastSent()
+	expected := fmt.Sprintf(
+		"[system:%d system:%d system:%d stake:%d]",
+		system.Instruction_Transfer,
+		system.Instruction_Allocate,
+		system.Instruction_Assign,
+		stakeInstructionSplit,
+	)
+	if types := stakeInstructionTypes(t, split); fmt.Sprint(types) != expected {
+		t.Fatalf("unexpected split instructions %v", types)
+	}
+	prefund := split.Message.Instructions[0]
+	if !split.Message.AccountKeys[prefund.Accounts[1]].Equals(splitStake) || binary.LittleEndian.Uint64(prefund.Data[4:]) != rent {
+		t.Fatal("split stake account must be prefunded with the rent exempt reserve")
+	}
+}
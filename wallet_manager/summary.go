@@ -0,0 +1,103 @@
+package wallet_manager
+
+import (
+	"fmt"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"strings"
+)
+
+// SummarizeTransaction renders tx in a human-readable form: fee payer, blockhash,
+// required signers with their signature status and one line per instruction.
+func SummarizeTransaction(tx *solana.Transaction) string {
+	var builder strings.Builder
+	message := tx.Message
+	if len(message.AccountKeys) > 0 {
+		fmt.Fprintf(&builder, "fee payer: %s\n", message.AccountKeys[0].String())
+	}
+	fmt.Fprintf(&builder, "recent blockhash: %s\n", message.RecentBlockhash.String())
+	builder.WriteString("signers:\n")
+	for idx, signer := range message.Signers() {
+		status := "missing"
+		if idx < len(tx.Signatures) && !tx.Signatures[idx].IsZero() {
+			status = "signed"
+		}
+		fmt.Fprintf(&builder, "  %s (%s)\n", signer.String(), status)
+	}
+	builder.WriteString("instructions:\n")
+	for idx, instruction := range message.Instructions {
+		fmt.Fprintf(&builder, "  #%d %s\n", idx, describeInstruction(&message, instruction))
+	}
+	return builder.String()
+}
+
+func describeInstruction(message *solana.Message, instruction solana.CompiledInstruction) string {
+	programID, err := message.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+	if err != nil {
+		return fmt.Sprintf("unknown program: %s", err.Error())
+	}
+	accounts := instruction.ResolveInstructionAccounts(message)
+	switch {
+	case programID.Equals(solana.SystemProgramID):
+		decoded, err := system.DecodeInstruction(accounts, instruction.Data)
+		if err != nil {
+			break
+		}
+		if transfer, ok := decoded.Impl.(*system.Transfer); ok {
+			return fmt.Sprintf(
+				"system transfer %s SOL (%d lamports) from %s to %s",
+				formatLamports(*transfer.Lamports),
+				*transfer.Lamports,
+				transfer.GetFundingAccount().PublicKey.String(),
+				transfer.GetRecipientAccount().PublicKey.String(),
+			)
+		}
+		return fmt.Sprintf("system %s", system.InstructionIDToName(decoded.TypeID.Uint32()))
+	case programID.Equals(solana.TokenProgramID):
+		decoded, err := token.DecodeInstruction(accounts, instruction.Data)
+		if err != nil {
+			break
+		}
+		switch impl := decoded.Impl.(type) {
+		case *token.Transfer:
+			return fmt.Sprintf(
+				"token transfer %d from %s to %s, owner %s",
+				*impl.Amount,
+				impl.GetSourceAccount().PublicKey.String(),
+				impl.GetDestinationAccount().PublicKey.String(),
+				impl.GetOwnerAccount().PublicKey.String(),
+			)
+		case *token.TransferChecked:
+			return fmt.Sprintf(
+				"token transfer %d of mint %s from %s to %s, owner %s",
+				*impl.Amount,
+				impl.GetMintAccount().PublicKey.String(),
+				impl.GetSourceAccount().PublicKey.String(),
+				impl.GetDestinationAccount().PublicKey.String(),
+				impl.GetOwnerAccount().PublicKey.String(),
+			)
+		}
+		return fmt.Sprintf("token %s", token.InstructionIDToName(decoded.TypeID.Uint8()))
+	case programID.Equals(MemoProgramID) || programID.Equals(MemoV1ProgramID):
+		return fmt.Sprintf("memo %q", string(instruction.Data))
+	case programID.Equals(solana.SPLAssociatedTokenAccountProgramID):
+		if len(accounts) > 3 {
+			return fmt.Sprintf(
+				"create associated token account %s for %s, mint %s",
+				accounts[1].PublicKey.String(),
+				accounts[2].PublicKey.String(),
+				accounts[3].PublicKey.String(),
+			)
+		}
+	case programID.Equals(StakeProgramID):
+		if len(accounts) > 0 {
+			return fmt.Sprintf("stake instruction on %s", accounts[0].PublicKey.String())
+		}
+	}
+	return fmt.Sprintf("program %s, %d accounts, %d bytes of data", programID.String(), len(accounts), len(instruction.Data))
+}
+
+func formatLamports(lamports uint64) string {
+	return fmt.Sprintf("%.9f", float64(lamports)/float64(solana.LAMPORTS_PER_SOL))
+}
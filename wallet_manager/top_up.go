@@ -0,0 +1,277 @@
+package wallet_manager
+
+import (
+	"encoding/json"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/pkg/errors"
+	"os"
+	"solana-go-wm/internal/atomicfile"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTopUpBatchSize  = 20
+	getMultipleAccountsMax = 100
+)
+
+var ErrTopUpTooFrequent = errors.New("top-up run requested before the minimal run interval passed")
+
+type TopUpSkipReason string
+
+const (
+	TopUpSkipWalletCap         TopUpSkipReason = "wallet_cap"
+	TopUpSkipTreasuryExhausted TopUpSkipReason = "treasury_exhausted"
+)
+
+// TopUpConfig describes when and how much fleet wallets are refilled. Wallets below
+// MinLamports are funded up to TargetLamports. Zero caps and intervals mean unlimited.
+type TopUpConfig struct {
+	MinLamports    uint64
+	TargetLamports uint64
+	// WalletDailyCap limits lamports sent to one wallet during a UTC day,
+	// WalletDailyCaps overrides it for particular wallets.
+	WalletDailyCap  uint64
+	WalletDailyCaps map[solana.PublicKey]uint64
+	// TreasuryReserve is kept on the treasury to pay transaction fees.
+	TreasuryReserve uint64
+	MinRunInterval  time.Duration
+	BatchSize       int
+}
+
+type TopUpFunding struct {
+	Wallet    solana.PublicKey
+	Balance   uint64
+	Lamports  uint64
+	Signature solana.Signature
+	Err       error
+}
+
+type TopUpSkip struct {
+	Wallet  solana.PublicKey
+	Balance uint64
+	Reason  TopUpSkipReason
+}
+
+type TopUpReport struct {
+	StartedAt     time.Time
+	Treasury      solana.PublicKey
+	Checked       int
+	Funded        []TopUpFunding
+	Failed        []TopUpFunding
+	Skipped       []TopUpSkip
+	Operations    []*OperationResult
+	TotalLamports uint64
+}
+
+// TopUpService refills fleet wallets from a treasury with batched transfers.
+type TopUpService struct {
+	Manager  *WalletManager
+	Treasury solana.PrivateKey
+	Config   TopUpConfig
+	Now      func() time.Time
+
+	path  string
+	mu    sync.Mutex
+	state topUpState
+}
+
+type topUpState struct {
+	LastRun time.Time                   `json:"lastRun"`
+	Day     string                      `json:"day"`
+	Funded  map[solana.PublicKey]uint64 `json:"funded"`
+}
+
+func NewTopUpService(wm *WalletManager, treasury solana.PrivateKey, config TopUpConfig) *TopUpService {
+	return &TopUpService{
+		Manager:  wm,
+		Treasury: treasury,
+		Config:   config,
+		Now:      time.Now,
+	}
+}
+
+// OpenTopUpService returns a service which keeps its last run and the lamports funded
+// during the current day in the file at path, so restarts do not reset the caps.
+func OpenTopUpService(path string, wm *WalletManager, treasury solana.PrivateKey, config TopUpConfig) (*TopUpService, error) {
+	service := NewTopUpService(wm, treasury, config)
+	service.path = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return service, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("failed to read top-up state %s. err: %s", path, err.Error())
+	}
+	if err = json.Unmarshal(data, &service.state); err != nil {
+		return nil, errors.Errorf("failed to parse top-up state %s. err: %s", path, err.Error())
+	}
+	return service, nil
+}
+
+// Run checks balances of wallets and funds the ones below the minimum. Transfers
+// which failed, including ones queued for approval, are reported in Failed. Failed
+// transfers that may still land count towards wallet caps. Only a completed run
+// starts the minimal run interval.
+func (s *TopUpService) Run(wallets []solana.PublicKey) (*TopUpReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	if !s.state.LastRun.IsZero() && s.Config.MinRunInterval > 0 && now.Sub(s.state.LastRun) < s.Config.MinRunInterval {
+		return nil, ErrTopUpTooFrequent
+	}
+	s.rollDay(now)
+
+	report := &TopUpReport{
+		StartedAt: now,
+		Treasury:  s.Treasury.PublicKey(),
+		Checked:   len(wallets),
+	}
+	balances, err := s.Manager.getBalances(wallets)
+	if err != nil {
+		return nil, err
+	}
+	treasuryBalance, err := s.Manager.Client.GetBalance(s.Manager.Context, s.Treasury.PublicKey(), s.Manager.Commitment)
+	if err != nil {
+		return nil, errors.Errorf("failed to get balance of treasury %s. err: %s", s.Treasury.PublicKey().String(), err.Error())
+	}
+	var available uint64
+	if treasuryBalance.Value > s.Config.TreasuryReserve {
+		available = treasuryBalance.Value - s.Config.TreasuryReserve
+	}
+
+	var pending []TopUpFunding
+	for idx, wallet := range wallets {
+		balance := balances[idx]
+		if balance >= s.Config.MinLamports || balance >= s.Config.TargetLamports {
+			continue
+		}
+		lamports := s.Config.TargetLamports - balance
+		if limit, ok := s.walletCap(wallet); ok {
+			if s.state.Funded[wallet] >= limit {
+				report.Skipped = append(report.Skipped, TopUpSkip{Wallet: wallet, Balance: balance, Reason: TopUpSkipWalletCap})
+				continue
+			}
+			if remaining := limit - s.state.Funded[wallet]; lamports > remaining {
+				lamports = remaining
+			}
+		}
+		if lamports > available {
+			report.Skipped = append(report.Skipped, TopUpSkip{Wallet: wallet, Balance: balance, Reason: TopUpSkipTreasuryExhausted})
+			continue
+		}
+		available -= lamports
+		pending = append(pending, TopUpFunding{Wallet: wallet, Balance: balance, Lamports: lamports})
+	}
+
+	batchSize := s.Config.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultTopUpBatchSize
+	}
+	for start := 0; start < len(pending); start += batchSize {
+		end := start + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+		var instructions []solana.Instruction
+		for _, funding := range batch {
+			instructions = append(instructions, makeTransferInstruction(s.Treasury.PublicKey(), funding.Wallet, funding.Lamports))
+		}
+		result, err := s.Manager.SendAndConfirmInstructions(
+			s.Treasury.PublicKey(),
+			instructions,
+			[]solana.PrivateKey{s.Treasury},
+		)
+		if result != nil {
+			report.Operations = append(report.Operations, result)
+		}
+		// a transfer whose outcome is unknown may still land, so it is counted as
+		// spent rather than funded again by the next run
+		spent := IsUnconfirmed(err) || (result != nil && !result.Simulated)
+		for _, funding := range batch {
+			if spent {
+				s.state.Funded[funding.Wallet] += funding.Lamports
+			}
+			if err != nil {
+				funding.Err = err
+				report.Failed = append(report.Failed, funding)
+				continue
+			}
+			funding.Signature = result.Signature()
+			report.Funded = append(report.Funded, funding)
+			report.TotalLamports += funding.Lamports
+		}
+		if spent {
+			if err = s.flush(); err != nil {
+				return report, errors.Errorf("failed to save top-up state. err: %s", err.Error())
+			}
+		}
+	}
+	s.state.LastRun = now
+	if err = s.flush(); err != nil {
+		return report, errors.Errorf("failed to save top-up state. err: %s", err.Error())
+	}
+	return report, nil
+}
+
+// FundedToday returns lamports sent to wallet by the service during the current UTC day.
+func (s *TopUpService) FundedToday(wallet solana.PublicKey) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollDay(s.Now())
+	return s.state.Funded[wallet]
+}
+
+func (s *TopUpService) walletCap(wallet solana.PublicKey) (uint64, bool) {
+	if limit, ok := s.Config.WalletDailyCaps[wallet]; ok {
+		return limit, limit > 0
+	}
+	return s.Config.WalletDailyCap, s.Config.WalletDailyCap > 0
+}
+
+func (s *TopUpService) rollDay(now time.Time) {
+	day := now.UTC().Format("2006-01-02")
+	if s.state.Day == day && s.state.Funded != nil {
+		return
+	}
+	s.state.Day = day
+	s.state.Funded = map[solana.PublicKey]uint64{}
+}
+
+func (s *TopUpService) flush() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(s.path, data)
+}
+
+func (wm *WalletManager) getBalances(accounts []solana.PublicKey) ([]uint64, error) {
+	var balances []uint64
+	for start := 0; start < len(accounts); start += getMultipleAccountsMax {
+		end := start + getMultipleAccountsMax
+		if end > len(accounts) {
+			end = len(accounts)
+		}
+		result, err := wm.Client.GetMultipleAccountsWithOpts(wm.Context, accounts[start:end], &rpc.GetMultipleAccountsOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: wm.Commitment,
+		})
+		if err != nil {
+			return nil, errors.Errorf("failed to get balances. err: %s", err.Error())
+		}
+		for idx := range accounts[start:end] {
+			var balance uint64
+			if idx < len(result.Value) && result.Value[idx] != nil {
+				balance = result.Value[idx].Lamports
+			}
+			balances = append(balances, balance)
+		}
+	}
+	return balances, nil
+}
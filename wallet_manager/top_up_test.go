@@ -0,0 +1,188 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"path/filepath"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+	"time"
+)
+
+func TestTopUpService_Run(t *testing.T) {
+	treasury := solana.NewWallet().PrivateKey
+	low := solana.NewWallet().PublicKey()
+	high := solana.NewWallet().PublicKey()
+	missing := solana.NewWallet().PublicKey()
+	poor := solana.NewWallet().PublicKey()
+
+	fake := fakerpc.New()
+	fake.Handle("getMultipleAccounts", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value([]interface{}{
+			fakerpc.Account(100, solana.SystemProgramID, nil),
+			fakerpc.Account(10000, solana.SystemProgramID, nil),
+			nil,
+			fakerpc.Account(0, solana.SystemProgramID, nil),
+		}), nil
+	})
+	fake.Handle("getBalance", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value(11000), nil
+	})
+	fake.Handle("getSignatureStatuses", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value([]interface{}{
+			map[string]interface{}{"slot": 5, "err": nil, "confirmationStatus": "confirmed"},
+		}), nil
+	})
+	manager := newFakeWalletManager(fake)
+	manager.ConfirmationDelay = 10 * time.Millisecond
+
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	service := NewTopUpService(manager, treasury, TopUpConfig{
+		MinLamports:     1000,
+		TargetLamports:  5000,
+		WalletDailyCap:  4000,
+		TreasuryReserve: 1000,
+		MinRunInterval:  time.Hour,
+		BatchSize:       1,
+	})
+	service.Now = func() time.Time { return now }
+	wallets := []solana.PublicKey{low, high, missing, poor}
+
+	report, err := service.Run(wallets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Funded) != 2 || report.TotalLamports != 8000 || fake.Calls("sendTransaction") != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].Wallet != poor || report.Skipped[0].Reason != TopUpSkipTreasuryExhausted {
+		t.Fatalf("expected poor wallet to be skipped, got %+v", report.Skipped)
+	}
+	if service.FundedToday(low) != 4000 {
+		t.Fatalf("expected 4000 lamports funded to low wallet, got %d", service.FundedToday(low))
+	}
+
+	if _, err = service.Run(wallets); err != ErrTopUpTooFrequent {
+		t.Fatalf("expected too frequent error, got %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	report, err = service.Run(wallets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Funded) != 1 || report.Funded[0].Wallet != poor {
+		t.Fatalf("expected only poor wallet to be funded, got %+v", report.Funded)
+	}
+	for _, skip := range report.Skipped {
+		if skip.Reason != TopUpSkipWalletCap {
+			t.Fatalf("expected wallet cap skips, got %+v", report.Skipped)
+		}
+	}
+}
+
+func newTopUpFake(balance uint64) *fakerpc.Client {
+	fake := fakerpc.New()
+	fake.Handle("getMultipleAccounts", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value([]interface{}{fakerpc.Account(balance, solana.SystemProgramID, nil)}), nil
+	})
+	fake.Handle("getBalance", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value(100000), nil
+	})
+	return fake
+}
+
+func TestTopUpService_RunFailedDoesNotStartInterval(t *testing.T) {
+	fake := newTopUpFake(0)
+	fake.Handle("getBalance", func(params []interface{}) (interface{}, error) {
+		return nil, errors.New("node is behind")
+	})
+	config := TopUpConfig{MinLamports: 1000, TargetLamports: 5000, MinRunInterval: time.Hour}
+	service := NewTopUpService(newFakeWalletManager(fake), solana.NewWallet().PrivateKey, config)
+	wallets := []solana.PublicKey{solana.NewWallet().PublicKey()}
+
+	if _, err := service.Run(wallets); err == nil {
+		t.Fatal("expected the treasury balance error")
+	}
+	fake.Handle("getBalance", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value(100000), nil
+	})
+	report, err := service.Run(wallets)
+	if err != nil {
+		t.Fatalf("a failed run must not start the run interval, got %v", err)
+	}
+	if len(report.Funded) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestTopUpService_RunUnconfirmedCountsTowardsCap(t *testing.T) {
+	fake := newTopUpFake(0)
+	fake.Handle("getSignatureStatuses", func(params []interface{}) (interface{}, error) {
+		return fakerpc.Value([]interface{}{
+			map[string]interface{}{"slot": 5, "err": nil, "confirmationStatus": "processed"},
+		}), nil
+	})
+	wallet := solana.NewWallet().PublicKey()
+	service := NewTopUpService(newFakeWalletManager(fake), solana.NewWallet().PrivateKey, TopUpConfig{
+		MinLamports:    1000,
+		TargetLamports: 5000,
+		WalletDailyCap: 5000,
+	})
+
+	report, err := service.Run([]solana.PublicKey{wallet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failed) != 1 || !IsUnconfirmed(report.Failed[0].Err) {
+		t.Fatalf("expected an unconfirmed transfer, got %+v", report)
+	}
+	if service.FundedToday(wallet) != 5000 {
+		t.Fatalf("unconfirmed transfer should count towards the cap, got %d", service.FundedToday(wallet))
+	}
+	report, err = service.Run([]solana.PublicKey{wallet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].Reason != TopUpSkipWalletCap || fake.Calls("sendTransaction") != 1 {
+		t.Fatalf("expected the capped wallet to be skipped, got %+v", report)
+	}
+}
+
+func TestTopUpService_OpenKeepsState(t *testing.T) {
+	fake := newTopUpFake(0)
+	path := filepath.Join(t.TempDir(), "top_up.json")
+	wallet := solana.NewWallet().PublicKey()
+	treasury := solana.NewWallet().PrivateKey
+	config := TopUpConfig{MinLamports: 1000, TargetLamports: 5000, WalletDailyCap: 8000, MinRunInterval: time.Hour}
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	service, err := OpenTopUpService(path, newFakeWalletManager(fake), treasury, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.Now = func() time.Time { return now }
+	if _, err = service.Run([]solana.PublicKey{wallet}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenTopUpService(path, newFakeWalletManager(fake), treasury, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.Now = func() time.Time { return now }
+	if reopened.FundedToday(wallet) != 5000 {
+		t.Fatalf("expected funded lamports to survive a restart, got %d", reopened.FundedToday(wallet))
+	}
+	if _, err = reopened.Run([]solana.PublicKey{wallet}); err != ErrTopUpTooFrequent {
+		t.Fatalf("expected the last run to survive a restart, got %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	report, err := reopened.Run([]solana.PublicKey{wallet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Funded) != 1 || report.Funded[0].Lamports != 3000 {
+		t.Fatalf("expected the funding to be limited by the remaining cap, got %+v", report.Funded)
+	}
+}
@@ -15,31 +15,49 @@ type WalletManager struct {
 	ConfirmationTimeout    time.Duration
 	ConfirmationDelay      time.Duration
 	SkipPreflight          bool
+	Journal                *Journal
+	Policy                 *PolicyEngine
+	Approvals              *ApprovalQueue
+	Operator               string
+	OperatorKey            solana.PublicKey
+	simulations            *simulationRecorder
+	idempotencyKey         string
+	idempotencyReserved    bool
+	policyReservation      *policyReservation
+	approvalChecked        bool
 }
 
 type SendLamportsInstructionParams struct {
 	From     solana.PrivateKey
 	To       solana.PublicKey
 	Lamports uint64
+	Memo     string
+	SignMemo bool
 }
 
 type SendSolInstructionParams struct {
-	From solana.PrivateKey
-	To   solana.PublicKey
-	Sol  float64
+	From     solana.PrivateKey
+	To       solana.PublicKey
+	Sol      float64
+	Memo     string
+	SignMemo bool
 }
 
 func (params *SendSolInstructionParams) toLamports() SendLamportsInstructionParams {
 	return SendLamportsInstructionParams{
-		params.From,
-		params.To,
-		uint64(params.Sol * float64(solana.LAMPORTS_PER_SOL)),
+		From:     params.From,
+		To:       params.To,
+		Lamports: uint64(params.Sol * float64(solana.LAMPORTS_PER_SOL)),
+		Memo:     params.Memo,
+		SignMemo: params.SignMemo,
 	}
 }
 
 type SendTokensInstructionParams struct {
-	From   solana.PrivateKey
-	To     solana.PublicKey
-	Mint   solana.PublicKey
-	Amount uint64
+	From     solana.PrivateKey
+	To       solana.PublicKey
+	Mint     solana.PublicKey
+	Amount   uint64
+	Memo     string
+	SignMemo bool
 }
@@ -43,15 +43,15 @@ func NewWalletManagerWithOpts(
 	}
 }
 
-func (wm *WalletManager) SendSol(from solana.PrivateKey, to solana.PublicKey, amountSol float64) (solana.Signature, error) {
-	return wm.SendSolTransaction(from, []SendSolInstructionParams{{from, to, amountSol}})
+func (wm *WalletManager) SendSol(from solana.PrivateKey, to solana.PublicKey, amountSol float64) (*OperationResult, error) {
+	return wm.SendSolTransaction(from, []SendSolInstructionParams{{From: from, To: to, Sol: amountSol}})
 }
 
-func (wm *WalletManager) SendLamports(from solana.PrivateKey, to solana.PublicKey, lamports uint64) (solana.Signature, error) {
-	return wm.SendLamportsTransaction(from, []SendLamportsInstructionParams{{from, to, lamports}})
+func (wm *WalletManager) SendLamports(from solana.PrivateKey, to solana.PublicKey, lamports uint64) (*OperationResult, error) {
+	return wm.SendLamportsTransaction(from, []SendLamportsInstructionParams{{From: from, To: to, Lamports: lamports}})
 }
 
-func (wm *WalletManager) SendSolTransaction(feePayer solana.PrivateKey, instructionsParams []SendSolInstructionParams) (solana.Signature, error) {
+func (wm *WalletManager) SendSolTransaction(feePayer solana.PrivateKey, instructionsParams []SendSolInstructionParams) (*OperationResult, error) {
 	var params []SendLamportsInstructionParams
 	for _, solParams := range instructionsParams {
 		params = append(params, solParams.toLamports())
@@ -59,11 +59,12 @@ func (wm *WalletManager) SendSolTransaction(feePayer solana.PrivateKey, instruct
 	return wm.SendLamportsTransaction(feePayer, params)
 }
 
-func (wm *WalletManager) SendLamportsTransaction(feePayer solana.PrivateKey, instructionsParams []SendLamportsInstructionParams) (solana.Signature, error) {
+func (wm *WalletManager) SendLamportsTransaction(feePayer solana.PrivateKey, instructionsParams []SendLamportsInstructionParams) (*OperationResult, error) {
 	var instructions []solana.Instruction
 	var signers []solana.PrivateKey
 	for _, params := range instructionsParams {
 		instructions = append(instructions, makeTransferInstruction(params.From.PublicKey(), params.To, params.Lamports))
+		instructions = AppendMemo(instructions, params.Memo, params.SignMemo, params.From.PublicKey())
 		signers = append(signers, params.From)
 	}
 	signers = appendSignerIfNotPresented(signers, feePayer)
@@ -74,7 +75,7 @@ func (wm *WalletManager) SendLamportsTransaction(feePayer solana.PrivateKey, ins
 	)
 }
 
-func (wm *WalletManager) SpreadLamports(from solana.PrivateKey, receivers []solana.PublicKey, lamports uint64) (solana.Signature, error) {
+func (wm *WalletManager) SpreadLamports(from solana.PrivateKey, receivers []solana.PublicKey, lamports uint64) (*OperationResult, error) {
 	var instructions []solana.Instruction
 	for _, receiver := range receivers {
 		instructions = append(instructions, makeTransferInstruction(from.PublicKey(), receiver, lamports))
@@ -86,18 +87,18 @@ func (wm *WalletManager) SpreadLamports(from solana.PrivateKey, receivers []sola
 	)
 }
 
-func (wm *WalletManager) SendAllSol(from solana.PrivateKey, to solana.PublicKey) (solana.Signature, error) {
+func (wm *WalletManager) SendAllSol(from solana.PrivateKey, to solana.PublicKey) (*OperationResult, error) {
 	return wm.CollectAllSol([]solana.PrivateKey{from}, to)
 }
 
-func (wm *WalletManager) CollectAllSol(fromWallets []solana.PrivateKey, to solana.PublicKey) (solana.Signature, error) {
+func (wm *WalletManager) CollectAllSol(fromWallets []solana.PrivateKey, to solana.PublicKey) (*OperationResult, error) {
 	if len(fromWallets) == 0 {
-		return solana.Signature{}, errors.New("no wallets to send from")
+		return nil, errors.New("no wallets to send from")
 	}
 	feePayer := fromWallets[0]
 	feeTx, err := wm.makeTransferTransaction(feePayer, to, 0)
 	if err != nil {
-		return solana.Signature{}, errors.Errorf(
+		return nil, errors.Errorf(
 			"failed to make transfer transaction from %s to %s",
 			feePayer.PublicKey().String(),
 			to.String(),
@@ -105,7 +106,7 @@ func (wm *WalletManager) CollectAllSol(fromWallets []solana.PrivateKey, to solan
 	}
 	getFeeResult, err := wm.Client.GetFeeForMessage(context.TODO(), feeTx.Message.ToBase64(), wm.Commitment)
 	if err != nil {
-		return solana.Signature{}, errors.Errorf("failed to get fee for transaction %s", feeTx.String())
+		return nil, errors.Errorf("failed to get fee for transaction %s", feeTx.String())
 	}
 	totalFee := *getFeeResult.Value * uint64(len(fromWallets))
 	var instructions []solana.Instruction
@@ -116,7 +117,7 @@ func (wm *WalletManager) CollectAllSol(fromWallets []solana.PrivateKey, to solan
 		}
 		balance, err := wm.Client.GetBalance(context.TODO(), from.PublicKey(), wm.Commitment)
 		if err != nil {
-			return solana.Signature{}, errors.Errorf("failed to get balance of %s", from.PublicKey().String())
+			return nil, errors.Errorf("failed to get balance of %s", from.PublicKey().String())
 		}
 		instructions = append(instructions, makeTransferInstruction(from.PublicKey(), to, balance.Value-fee))
 	}
@@ -154,16 +155,16 @@ func makeTransferInstruction(from, to solana.PublicKey, lamports uint64) solana.
 		Build()
 }
 
-func (wm *WalletManager) SendTokens(feePayer solana.PrivateKey, to, mint solana.PublicKey, amount uint64) (solana.Signature, error) {
-	return wm.SendTokensTransaction(feePayer, []SendTokensInstructionParams{{feePayer, to, mint, amount}})
+func (wm *WalletManager) SendTokens(feePayer solana.PrivateKey, to, mint solana.PublicKey, amount uint64) (*OperationResult, error) {
+	return wm.SendTokensTransaction(feePayer, []SendTokensInstructionParams{{From: feePayer, To: to, Mint: mint, Amount: amount}})
 }
 
-func (wm *WalletManager) SendTokensTransaction(feePayer solana.PrivateKey, instructionsParams []SendTokensInstructionParams) (solana.Signature, error) {
+func (wm *WalletManager) SendTokensTransaction(feePayer solana.PrivateKey, instructionsParams []SendTokensInstructionParams) (*OperationResult, error) {
 	var instructions []solana.Instruction
 	var signers []solana.PrivateKey
 	for _, params := range instructionsParams {
 		processAddress := func(to solana.PublicKey) (solana.PublicKey, error) {
-			atokAddress, fromAtokInst, err := wm.getOrCreateAssociatedTokenAddress(params.From, to, params.Mint)
+			atokAddress, fromAtokInst, err := wm.getOrCreateAssociatedTokenAddress(params.From.PublicKey(), to, params.Mint)
 			if err != nil {
 				return solana.PublicKey{}, errors.Errorf(
 					"failed to find associated token address for %s. err: %s",
@@ -178,11 +179,11 @@ func (wm *WalletManager) SendTokensTransaction(feePayer solana.PrivateKey, instr
 		}
 		fromAssociatedAddress, err := processAddress(params.From.PublicKey())
 		if err != nil {
-			return solana.Signature{}, err
+			return nil, err
 		}
 		toAssociatedAddress, err := processAddress(params.To)
 		if err != nil {
-			return solana.Signature{}, err
+			return nil, err
 		}
 		instruction := token.NewTransferInstructionBuilder().
 			SetAmount(params.Amount).
@@ -191,6 +192,7 @@ func (wm *WalletManager) SendTokensTransaction(feePayer solana.PrivateKey, instr
 			SetOwnerAccount(params.From.PublicKey()).
 			Build()
 		instructions = append(instructions, instruction)
+		instructions = AppendMemo(instructions, params.Memo, params.SignMemo, params.From.PublicKey())
 		signers = append(signers, params.From)
 	}
 	signers = appendSignerIfNotPresented(signers, feePayer)
@@ -202,7 +204,7 @@ func (wm *WalletManager) SendTokensTransaction(feePayer solana.PrivateKey, instr
 }
 
 func (wm *WalletManager) getOrCreateAssociatedTokenAddress(
-	payer solana.PrivateKey,
+	payer,
 	account,
 	mint solana.PublicKey,
 ) (solana.PublicKey, *atok.Instruction, error) {
@@ -216,7 +218,7 @@ func (wm *WalletManager) getOrCreateAssociatedTokenAddress(
 	var createInstruction *atok.Instruction
 	if err != nil {
 		createInstruction = atok.NewCreateInstructionBuilder().
-			SetPayer(payer.PublicKey()).
+			SetPayer(payer).
 			SetMint(mint).
 			SetWallet(account).
 			Build()
@@ -228,10 +230,52 @@ func (wm *WalletManager) SendAndConfirmInstructions(
 	feePayer solana.PublicKey,
 	instructions []solana.Instruction,
 	signers []solana.PrivateKey,
-) (solana.Signature, error) {
+) (*OperationResult, error) {
+	tx, err := wm.BuildTransaction(feePayer, instructions)
+	if err != nil {
+		return nil, err
+	}
+	return wm.signAndSend(tx, signers)
+}
+
+func (wm *WalletManager) signAndSend(tx *solana.Transaction, signers []solana.PrivateKey) (*OperationResult, error) {
+	if wm.idempotencyKey != "" && !wm.idempotencyReserved && !wm.IsDryRun() {
+		reserved, release, err := wm.reserveIdempotencyKey()
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return reserved.signAndSend(tx, signers)
+	}
+	if wm.Approvals != nil && !wm.approvalChecked {
+		// queued before signing, so no signed copy waits in the approval queue
+		checked, err := wm.submitForApproval(tx)
+		if err != nil {
+			return nil, err
+		}
+		return checked.signAndSend(tx, signers)
+	}
+	if wm.Policy != nil && wm.policyReservation == nil {
+		reserved, err := wm.reservePolicy(tx)
+		if err != nil {
+			return nil, err
+		}
+		defer reserved.policyReservation.finish()
+		return reserved.signAndSend(tx, signers)
+	}
+	if err := SignTransaction(tx, signers); err != nil {
+		return nil, err
+	}
+	return wm.SendAndConfirmTransaction(tx)
+}
+
+func (wm *WalletManager) BuildTransaction(
+	feePayer solana.PublicKey,
+	instructions []solana.Instruction,
+) (*solana.Transaction, error) {
 	recent, err := wm.Client.GetRecentBlockhash(wm.Context, wm.Commitment)
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, err
 	}
 	txBuilder := solana.NewTransactionBuilder().
 		SetRecentBlockHash(recent.Value.Blockhash).
@@ -239,35 +283,52 @@ func (wm *WalletManager) SendAndConfirmInstructions(
 	for _, instruction := range instructions {
 		txBuilder.AddInstruction(instruction)
 	}
-	tx, err := txBuilder.Build()
-	if err != nil {
-		return solana.Signature{}, err
-	}
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		for _, candidate := range signers {
-			if candidate.PublicKey().Equals(key) {
-				return &candidate
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		return solana.Signature{}, err
-	}
-	return wm.SendAndConfirmTransaction(tx)
+	return txBuilder.Build()
 }
 
+// SendAndConfirmTransaction is the single point every transaction is sent through,
+// so the approval thresholds and the spending policy apply to transactions signed
+// elsewhere as well.
 func (wm *WalletManager) SendAndConfirmTransaction(
 	tx *solana.Transaction,
-) (solana.Signature, error) {
+) (*OperationResult, error) {
+	if wm.Approvals != nil && !wm.approvalChecked {
+		checked, err := wm.submitForApproval(tx)
+		if err != nil {
+			return nil, err
+		}
+		return checked.SendAndConfirmTransaction(tx)
+	}
+	if wm.Policy != nil && wm.policyReservation == nil {
+		reserved, err := wm.reservePolicy(tx)
+		if err != nil {
+			return nil, err
+		}
+		defer reserved.policyReservation.finish()
+		return reserved.SendAndConfirmTransaction(tx)
+	}
+	if wm.IsDryRun() {
+		return wm.dryRunTransaction(tx)
+	}
+	if wm.idempotencyKey != "" {
+		return wm.sendJournaled(tx)
+	}
 	sig, err := wm.Client.SendTransactionWithOpts(wm.Context, tx, rpc.TransactionOpts{
 		SkipPreflight:       wm.SkipPreflight,
 		PreflightCommitment: wm.Commitment,
 	})
+	if isRejectedSend(err) {
+		return nil, err
+	}
+	wm.markPolicySent()
+	if err != nil {
+		return nil, &UnconfirmedError{Signature: tx.Signatures[0], Err: err}
+	}
+	sig, err = wm.awaitSignaturesConfirmation([]solana.Signature{sig})
 	if err != nil {
-		return solana.Signature{}, err
+		return nil, &UnconfirmedError{Signature: tx.Signatures[0], Err: err}
 	}
-	return wm.awaitSignaturesConfirmation([]solana.Signature{sig})
+	return wm.fetchOperationResult(tx, sig)
 }
 
 func (wm *WalletManager) awaitSignaturesConfirmation(
@@ -34,7 +34,7 @@ func TestWalletManager_SendLamports(t *testing.T) {
 		})
 	}
 
-	sig, err := wm.SendLamportsTransaction(from.PrivateKey, params)
+	operation, err := wm.SendLamportsTransaction(from.PrivateKey, params)
 	if err != nil {
 		t.Fatalf("failed to spread lamports. err: %s", err.Error())
 	}
@@ -48,7 +48,7 @@ func TestWalletManager_SendLamports(t *testing.T) {
 			t.Fatalf("account %s balance is %d != %d", receiver.String(), result.Value, lamportsPerReceiver)
 		}
 	}
-	t.Log(sig.String())
+	t.Log(operation.Signature().String())
 }
 
 func TestWalletManager_SendAllSol(t *testing.T) {
@@ -59,7 +59,7 @@ func TestWalletManager_SendAllSol(t *testing.T) {
 		t.Fatal(err)
 	}
 	to := solana.NewWallet()
-	sig, err := wm.SendAllSol(from.PrivateKey, to.PublicKey())
+	operation, err := wm.SendAllSol(from.PrivateKey, to.PublicKey())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -70,7 +70,7 @@ func TestWalletManager_SendAllSol(t *testing.T) {
 	if result.Value != 0 {
 		t.Fatalf("sender %s balance is not zero", from.PublicKey().String())
 	}
-	t.Log(sig)
+	t.Log(operation.Signature())
 }
 
 func airdrop(receiver solana.PublicKey, lamports uint64) (solana.Signature, error) {
@@ -0,0 +1,62 @@
+package wallet_manager
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+)
+
+func (wm *WalletManager) WrapSol(owner solana.PrivateKey, lamports uint64) (*OperationResult, error) {
+	instructions, _, err := wm.WrapSolInstructions(owner.PublicKey(), owner.PublicKey(), lamports)
+	if err != nil {
+		return nil, err
+	}
+	return wm.SendAndConfirmInstructions(owner.PublicKey(), instructions, []solana.PrivateKey{owner})
+}
+
+func (wm *WalletManager) UnwrapSol(owner solana.PrivateKey) (*OperationResult, error) {
+	instructions, err := UnwrapSolInstructions(owner.PublicKey(), owner.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	return wm.SendAndConfirmInstructions(owner.PublicKey(), instructions, []solana.PrivateKey{owner})
+}
+
+// WrapSolInstructions returns instructions moving lamports from payer into the wrapped SOL
+// associated token account of owner, creating the account if it does not exist yet.
+// payer has to sign the transaction the instructions are embedded in.
+func (wm *WalletManager) WrapSolInstructions(
+	payer,
+	owner solana.PublicKey,
+	lamports uint64,
+) ([]solana.Instruction, solana.PublicKey, error) {
+	var instructions []solana.Instruction
+	ata, createInstruction, err := wm.getOrCreateAssociatedTokenAddress(payer, owner, solana.WrappedSol)
+	if err != nil {
+		return nil, solana.PublicKey{}, err
+	}
+	if createInstruction != nil {
+		instructions = append(instructions, createInstruction)
+	}
+	instructions = append(
+		instructions,
+		makeTransferInstruction(payer, ata, lamports),
+		token.NewSyncNativeInstructionBuilder().SetTokenAccount(ata).Build(),
+	)
+	return instructions, ata, nil
+}
+
+// UnwrapSolInstructions closes the wrapped SOL associated token account of owner
+// and sends all of its lamports to destination. owner has to sign.
+func UnwrapSolInstructions(owner, destination solana.PublicKey) ([]solana.Instruction, error) {
+	ata, _, err := solana.FindAssociatedTokenAddress(owner, solana.WrappedSol)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{
+		token.NewCloseAccountInstructionBuilder().
+			SetAccount(ata).
+			SetDestinationAccount(destination).
+			SetOwnerAccount(owner).
+			Build(),
+	}, nil
+}
@@ -0,0 +1,83 @@
+package wallet_manager
+
+import (
+	"encoding/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"solana-go-wm/internal/fakerpc"
+	"testing"
+)
+
+func TestWalletManager_WrapSol(t *testing.T) {
+	fake := fakerpc.New()
+	manager := newFakeWalletManager(fake)
+	owner := solana.NewWallet().PrivateKey
+	ata, _, err := solana.FindAssociatedTokenAddress(owner.PublicKey(), solana.WrappedSol)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = manager.WrapSol(owner, solana.LAMPORTS_PER_SOL); err != nil {
+		t.Fatal(err)
+	}
+	tx := fake.LastSent()
+	if len(tx.Message.Instructions) != 3 {
+		t.Fatalf("expected ata creation, transfer and sync native, got %d instructions", len(tx.Message.Instructions))
+	}
+	programs := []solana.PublicKey{solana.SPLAssociatedTokenAccountProgramID, solana.SystemProgramID, solana.TokenProgramID}
+	for idx, instruction := range tx.Message.Instructions {
+		program, err := tx.Message.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !program.Equals(programs[idx]) {
+			t.Fatalf("instruction %d: expected program %s, got %s", idx, programs[idx], program)
+		}
+	}
+	transfer := tx.Message.Instructions[1]
+	if binary.LittleEndian.Uint32(transfer.Data) != system.Instruction_Transfer ||
+		binary.LittleEndian.Uint64(transfer.Data[4:]) != solana.LAMPORTS_PER_SOL ||
+		!tx.Message.AccountKeys[transfer.Accounts[1]].Equals(ata) {
+		t.Fatal("expected lamports to be transferred into the wrapped SOL account")
+	}
+	syncNative := tx.Message.Instructions[2]
+	if syncNative.Data[0] != token.Instruction_SyncNative || !tx.Message.AccountKeys[syncNative.Accounts[0]].Equals(ata) {
+		t.Fatal("expected the wrapped SOL account to be synced")
+	}
+
+	fake.SetTokenAccount(ata, solana.WrappedSol, owner.PublicKey(), solana.LAMPORTS_PER_SOL)
+	if _, err = manager.WrapSol(owner, solana.LAMPORTS_PER_SOL); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.LastSent().Message.Instructions) != 2 {
+		t.Fatal("an existing wrapped SOL account must not be created again")
+	}
+}
+
+func TestWalletManager_UnwrapSol(t *testing.T) {
+	fake := fakerpc.New()
+	manager := newFakeWalletManager(fake)
+	owner := solana.NewWallet().PrivateKey
+	ata, _, err := solana.FindAssociatedTokenAddress(owner.PublicKey(), solana.WrappedSol)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = manager.UnwrapSol(owner); err != nil {
+		t.Fatal(err)
+	}
+	tx := fake.LastSent()
+	if len(tx.Message.Instructions) != 1 {
+		t.Fatalf("expected a single close instruction, got %d", len(tx.Message.Instructions))
+	}
+	closeAccount := tx.Message.Instructions[0]
+	accounts := closeAccount.ResolveInstructionAccounts(&tx.Message)
+	if closeAccount.Data[0] != token.Instruction_CloseAccount ||
+		!accounts[0].PublicKey.Equals(ata) ||
+		!accounts[1].PublicKey.Equals(owner.PublicKey()) ||
+		!accounts[2].PublicKey.Equals(owner.PublicKey()) ||
+		!accounts[2].IsSigner {
+		t.Fatalf("unexpected close instruction accounts %v", accounts)
+	}
+}
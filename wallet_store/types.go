@@ -0,0 +1,51 @@
+package wallet_store
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"sync"
+	"time"
+)
+
+type Wallet struct {
+	Address   solana.PublicKey
+	Label     string
+	Group     string
+	Tags      []string
+	CreatedAt time.Time
+}
+
+type ExportedWallet struct {
+	PrivateKey string   `json:"privateKey"`
+	Label      string   `json:"label"`
+	Group      string   `json:"group"`
+	Tags       []string `json:"tags"`
+}
+
+// WalletStore keeps wallets in a local JSON file. Private keys are encrypted with
+// AES-GCM under a key derived from the passphrase with scrypt.
+type WalletStore struct {
+	path    string
+	key     []byte
+	file    storeFile
+	mu      sync.Mutex
+	wallets map[solana.PublicKey]*storedWallet
+}
+
+type storeFile struct {
+	Version int             `json:"version"`
+	Salt    []byte          `json:"salt"`
+	ScryptN int             `json:"scryptN"`
+	ScryptR int             `json:"scryptR"`
+	ScryptP int             `json:"scryptP"`
+	Check   []byte          `json:"check"`
+	Wallets []*storedWallet `json:"wallets"`
+}
+
+type storedWallet struct {
+	Address    string    `json:"address"`
+	Label      string    `json:"label"`
+	Group      string    `json:"group"`
+	Tags       []string  `json:"tags"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
@@ -0,0 +1,415 @@
+package wallet_store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"github.com/gagliardetto/solana-go"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+	"os"
+	"solana-go-wm/internal/atomicfile"
+	"sort"
+	"time"
+)
+
+const (
+	storeVersion   = 1
+	defaultScryptN = 1 << 15
+	scryptR        = 8
+	scryptP        = 1
+)
+
+var (
+	ErrWrongPassphrase = errors.New("wrong wallet store passphrase")
+	ErrWalletNotFound  = errors.New("wallet not found")
+	checkPlaintext     = []byte("solana-go-wm wallet store")
+)
+
+func OpenWalletStore(path string, passphrase []byte) (*WalletStore, error) {
+	return OpenWalletStoreWithOpts(path, passphrase, defaultScryptN)
+}
+
+// OpenWalletStoreWithOpts opens the store at path or creates it when the file does
+// not exist. scryptN is used only for new stores, existing ones keep their own.
+func OpenWalletStoreWithOpts(path string, passphrase []byte, scryptN int) (*WalletStore, error) {
+	store := &WalletStore{
+		path:    path,
+		wallets: map[solana.PublicKey]*storedWallet{},
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, store.init(passphrase, scryptN)
+	}
+	if err != nil {
+		return nil, errors.Errorf("failed to read wallet store %s. err: %s", path, err.Error())
+	}
+	if err = json.Unmarshal(data, &store.file); err != nil {
+		return nil, errors.Errorf("failed to parse wallet store %s. err: %s", path, err.Error())
+	}
+	if store.file.Version != storeVersion {
+		return nil, errors.Errorf("unsupported wallet store version %d", store.file.Version)
+	}
+	store.key, err = scrypt.Key(passphrase, store.file.Salt, store.file.ScryptN, store.file.ScryptR, store.file.ScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	check, err := store.decrypt(store.file.Check, nil)
+	if err != nil || !bytes.Equal(check, checkPlaintext) {
+		return nil, ErrWrongPassphrase
+	}
+	for _, wallet := range store.file.Wallets {
+		address, err := solana.PublicKeyFromBase58(wallet.Address)
+		if err != nil {
+			return nil, errors.Errorf("invalid wallet address %s. err: %s", wallet.Address, err.Error())
+		}
+		store.wallets[address] = wallet
+	}
+	return store, nil
+}
+
+func (store *WalletStore) Generate(label, group string, tags ...string) (Wallet, error) {
+	return store.Import(solana.NewWallet().PrivateKey, label, group, tags...)
+}
+
+func (store *WalletStore) GenerateMany(count int, group string, tags ...string) ([]Wallet, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	var wallets []Wallet
+	for i := 0; i < count; i++ {
+		wallet, err := store.add(solana.NewWallet().PrivateKey, "", group, tags)
+		if err != nil {
+			store.rollback(wallets)
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	if err := store.flush(); err != nil {
+		store.rollback(wallets)
+		return nil, err
+	}
+	return wallets, nil
+}
+
+func (store *WalletStore) Import(key solana.PrivateKey, label, group string, tags ...string) (Wallet, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	wallet, err := store.add(key, label, group, tags)
+	if err != nil {
+		return Wallet{}, err
+	}
+	if err = store.flush(); err != nil {
+		store.rollback([]Wallet{wallet})
+		return Wallet{}, err
+	}
+	return wallet, nil
+}
+
+func (store *WalletStore) ImportWallets(exported []ExportedWallet) ([]Wallet, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	var wallets []Wallet
+	for _, item := range exported {
+		key, err := solana.PrivateKeyFromBase58(item.PrivateKey)
+		if err != nil {
+			store.rollback(wallets)
+			return nil, errors.Errorf("invalid private key. err: %s", err.Error())
+		}
+		wallet, err := store.add(key, item.Label, item.Group, item.Tags)
+		if err != nil {
+			store.rollback(wallets)
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	if err := store.flush(); err != nil {
+		store.rollback(wallets)
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// ExportWallets returns wallets with their private keys in plain base58.
+func (store *WalletStore) ExportWallets(wallets []Wallet) ([]ExportedWallet, error) {
+	var exported []ExportedWallet
+	for _, wallet := range wallets {
+		key, err := store.Signer(wallet.Address)
+		if err != nil {
+			return nil, err
+		}
+		exported = append(exported, ExportedWallet{
+			PrivateKey: key.String(),
+			Label:      wallet.Label,
+			Group:      wallet.Group,
+			Tags:       wallet.Tags,
+		})
+	}
+	return exported, nil
+}
+
+func (store *WalletStore) Remove(address solana.PublicKey) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	wallet, ok := store.wallets[address]
+	if !ok {
+		return ErrWalletNotFound
+	}
+	delete(store.wallets, address)
+	if err := store.flush(); err != nil {
+		store.wallets[address] = wallet
+		return err
+	}
+	return nil
+}
+
+func (store *WalletStore) Get(address solana.PublicKey) (Wallet, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	wallet, ok := store.wallets[address]
+	if !ok {
+		return Wallet{}, false
+	}
+	return wallet.toWallet(address), true
+}
+
+func (store *WalletStore) List() []Wallet {
+	return store.filter(func(wallet *storedWallet) bool { return true })
+}
+
+func (store *WalletStore) ByGroup(group string) []Wallet {
+	return store.filter(func(wallet *storedWallet) bool { return wallet.Group == group })
+}
+
+// ByTag returns wallets having every one of tags.
+func (store *WalletStore) ByTag(tags ...string) []Wallet {
+	return store.filter(func(wallet *storedWallet) bool {
+		for _, tag := range tags {
+			if !hasTag(wallet.Tags, tag) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (store *WalletStore) SetLabel(address solana.PublicKey, label string) error {
+	return store.update(address, func(wallet *storedWallet) { wallet.Label = label })
+}
+
+func (store *WalletStore) SetGroup(address solana.PublicKey, group string) error {
+	return store.update(address, func(wallet *storedWallet) { wallet.Group = group })
+}
+
+func (store *WalletStore) AddTags(address solana.PublicKey, tags ...string) error {
+	return store.update(address, func(wallet *storedWallet) {
+		for _, tag := range tags {
+			if !hasTag(wallet.Tags, tag) {
+				wallet.Tags = append(wallet.Tags, tag)
+			}
+		}
+	})
+}
+
+func (store *WalletStore) RemoveTags(address solana.PublicKey, tags ...string) error {
+	return store.update(address, func(wallet *storedWallet) {
+		var kept []string
+		for _, tag := range wallet.Tags {
+			if !hasTag(tags, tag) {
+				kept = append(kept, tag)
+			}
+		}
+		wallet.Tags = kept
+	})
+}
+
+// Signer decrypts the private key of address so it can be passed to
+// WalletManager and AuctionHouseActor operations. The address is authenticated
+// together with the key, so a ciphertext moved to another wallet does not decrypt.
+func (store *WalletStore) Signer(address solana.PublicKey) (solana.PrivateKey, error) {
+	store.mu.Lock()
+	wallet, ok := store.wallets[address]
+	store.mu.Unlock()
+	if !ok {
+		return nil, errors.Wrap(ErrWalletNotFound, address.String())
+	}
+	key, err := store.decrypt(wallet.Ciphertext, address.Bytes())
+	if err != nil {
+		return nil, errors.Errorf("failed to decrypt wallet %s. err: %s", address.String(), err.Error())
+	}
+	if len(key) != 64 || !solana.PrivateKey(key).PublicKey().Equals(address) {
+		return nil, errors.Errorf("decrypted key does not belong to wallet %s", address.String())
+	}
+	return solana.PrivateKey(key), nil
+}
+
+func (store *WalletStore) Signers(wallets []Wallet) ([]solana.PrivateKey, error) {
+	var signers []solana.PrivateKey
+	for _, wallet := range wallets {
+		signer, err := store.Signer(wallet.Address)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+func (store *WalletStore) SignersByTag(tags ...string) ([]solana.PrivateKey, error) {
+	return store.Signers(store.ByTag(tags...))
+}
+
+func (store *WalletStore) init(passphrase []byte, scryptN int) error {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return err
+	}
+	store.key = key
+	check, err := store.encrypt(checkPlaintext, nil)
+	if err != nil {
+		return err
+	}
+	store.file = storeFile{
+		Version: storeVersion,
+		Salt:    salt,
+		ScryptN: scryptN,
+		ScryptR: scryptR,
+		ScryptP: scryptP,
+		Check:   check,
+	}
+	return store.flush()
+}
+
+func (store *WalletStore) add(key solana.PrivateKey, label, group string, tags []string) (Wallet, error) {
+	address := key.PublicKey()
+	if _, ok := store.wallets[address]; ok {
+		return Wallet{}, errors.Errorf("wallet %s already exists", address.String())
+	}
+	ciphertext, err := store.encrypt(key, address.Bytes())
+	if err != nil {
+		return Wallet{}, err
+	}
+	wallet := &storedWallet{
+		Address:    address.String(),
+		Label:      label,
+		Group:      group,
+		Tags:       append([]string(nil), tags...),
+		CreatedAt:  time.Now().UTC(),
+		Ciphertext: ciphertext,
+	}
+	store.wallets[address] = wallet
+	return wallet.toWallet(address), nil
+}
+
+func (store *WalletStore) update(address solana.PublicKey, change func(wallet *storedWallet)) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	wallet, ok := store.wallets[address]
+	if !ok {
+		return errors.Wrap(ErrWalletNotFound, address.String())
+	}
+	previous := *wallet
+	previous.Tags = append([]string(nil), wallet.Tags...)
+	change(wallet)
+	if err := store.flush(); err != nil {
+		*wallet = previous
+		return err
+	}
+	return nil
+}
+
+// rollback removes wallets added by a call whose changes could not be written.
+func (store *WalletStore) rollback(wallets []Wallet) {
+	for _, wallet := range wallets {
+		delete(store.wallets, wallet.Address)
+	}
+}
+
+func (store *WalletStore) filter(match func(wallet *storedWallet) bool) []Wallet {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	var wallets []Wallet
+	for address, wallet := range store.wallets {
+		if match(wallet) {
+			wallets = append(wallets, wallet.toWallet(address))
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		if !wallets[i].CreatedAt.Equal(wallets[j].CreatedAt) {
+			return wallets[i].CreatedAt.Before(wallets[j].CreatedAt)
+		}
+		return wallets[i].Address.String() < wallets[j].Address.String()
+	})
+	return wallets
+}
+
+func (store *WalletStore) encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := store.cipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (store *WalletStore) decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	gcm, err := store.cipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, additionalData)
+}
+
+func (store *WalletStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(store.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (store *WalletStore) flush() error {
+	store.file.Wallets = nil
+	for _, wallet := range store.wallets {
+		store.file.Wallets = append(store.file.Wallets, wallet)
+	}
+	sort.Slice(store.file.Wallets, func(i, j int) bool {
+		return store.file.Wallets[i].Address < store.file.Wallets[j].Address
+	})
+	data, err := json.MarshalIndent(store.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(store.path, data)
+}
+
+func (wallet *storedWallet) toWallet(address solana.PublicKey) Wallet {
+	return Wallet{
+		Address:   address,
+		Label:     wallet.Label,
+		Group:     wallet.Group,
+		Tags:      append([]string(nil), wallet.Tags...),
+		CreatedAt: wallet.CreatedAt,
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, candidate := range tags {
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package wallet_store
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testScryptN = 1 << 4
+
+func openTestStore(t *testing.T, name string, passphrase string) *WalletStore {
+	store, err := OpenWalletStoreWithOpts(filepath.Join(t.TempDir(), name), []byte(passphrase), testScryptN)
+	if err != nil {
+		t.Fatalf("failed to open wallet store. err: %s", err.Error())
+	}
+	return store
+}
+
+func TestWalletStore_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallets.json")
+	store, err := OpenWalletStoreWithOpts(path, []byte("secret"), testScryptN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet, err := store.Generate("treasury", "ops", "hot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.GenerateMany(3, "bots", "hot", "bidder"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = OpenWalletStore(path, []byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected wrong passphrase error, got %v", err)
+	}
+	reopened, err := OpenWalletStore(path, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.List()) != 4 {
+		t.Fatalf("expected 4 wallets, got %d", len(reopened.List()))
+	}
+	got, ok := reopened.Get(wallet.Address)
+	if !ok || got.Label != "treasury" {
+		t.Fatalf("unexpected wallet %+v", got)
+	}
+	signer, err := reopened.Signer(wallet.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !signer.PublicKey().Equals(wallet.Address) {
+		t.Fatalf("signer %s does not match wallet %s", signer.PublicKey(), wallet.Address)
+	}
+}
+
+func TestWalletStore_Queries(t *testing.T) {
+	store := openTestStore(t, "wallets.json", "secret")
+	bots, err := store.GenerateMany(2, "bots", "bidder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Generate("", "ops", "hot"); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.ByGroup("bots")) != 2 || len(store.ByTag("bidder")) != 2 {
+		t.Fatal("unexpected group or tag query result")
+	}
+	if err = store.AddTags(bots[0].Address, "hot"); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.ByTag("bidder", "hot")) != 1 {
+		t.Fatal("expected one wallet tagged bidder and hot")
+	}
+	if err = store.RemoveTags(bots[0].Address, "bidder"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.SetGroup(bots[1].Address, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.ByTag("bidder")) != 1 || len(store.ByGroup("ops")) != 2 {
+		t.Fatal("unexpected query result after update")
+	}
+	signers, err := store.SignersByTag("hot")
+	if err != nil || len(signers) != 2 {
+		t.Fatalf("expected 2 hot signers, got %d. err: %v", len(signers), err)
+	}
+	if err = store.Remove(bots[0].Address); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Remove(bots[0].Address); !errors.Is(err, ErrWalletNotFound) {
+		t.Fatalf("expected wallet not found, got %v", err)
+	}
+}
+
+func TestWalletStore_ExportImport(t *testing.T) {
+	source := openTestStore(t, "a.json", "a")
+	if _, err := source.GenerateMany(2, "bots", "bidder"); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := source.ExportWallets(source.List())
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := openTestStore(t, "b.json", "b")
+	if _, err = target.ImportWallets(exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(target.ByTag("bidder")) != 2 {
+		t.Fatal("expected imported wallets to keep their tags")
+	}
+	if _, err = target.ImportWallets(exported); err == nil {
+		t.Fatal("expected duplicate import to fail")
+	}
+}
+
+func TestWalletStore_SignerChecksAddress(t *testing.T) {
+	store := openTestStore(t, "wallets.json", "secret")
+	wallets, err := store.GenerateMany(2, "bots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := store.wallets[wallets[0].Address], store.wallets[wallets[1].Address]
+	first.Ciphertext, second.Ciphertext = second.Ciphertext, first.Ciphertext
+	if _, err = store.Signer(wallets[0].Address); err == nil {
+		t.Fatal("expected a key stored under another address to be refused")
+	}
+}
+
+func TestWalletStore_RollbackOnFailedWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenWalletStoreWithOpts(filepath.Join(dir, "wallets.json"), []byte("secret"), testScryptN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet, err := store.Generate("treasury", "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Generate("", "ops"); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if _, err = store.GenerateMany(2, "bots"); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if len(store.List()) != 1 {
+		t.Fatalf("failed writes must not add wallets, got %d", len(store.List()))
+	}
+	if err = store.SetLabel(wallet.Address, "cold"); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if got, _ := store.Get(wallet.Address); got.Label != "treasury" {
+		t.Fatalf("failed write must not change the label, got %s", got.Label)
+	}
+	if err = store.Remove(wallet.Address); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if _, ok := store.Get(wallet.Address); !ok {
+		t.Fatal("failed write must not remove the wallet")
+	}
+}